@@ -1,6 +1,11 @@
 package prombolt
 
 import (
+	"context"
+	"runtime/trace"
+	"sync"
+	"time"
+
 	"github.com/boltdb/bolt"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -12,288 +17,243 @@ var _ prometheus.Collector = &bucketStatsCollector{}
 type bucketStatsCollector struct {
 	name    string
 	db      *bolt.DB
-	forEach func(fn forEachBucketStatsFunc) error
-
-	LogicalBranchPages                *prometheus.Desc
-	PhysicalBranchOverflowPages       *prometheus.Desc
-	LogicalLeafPages                  *prometheus.Desc
-	PhysicalLeafOverflowPages         *prometheus.Desc
-	Keys                              *prometheus.Desc
-	Depth                             *prometheus.Desc
-	PhysicalBranchPagesAllocatedBytes *prometheus.Desc
-	PhysicalBranchPagesInUseBytes     *prometheus.Desc
-	PhysicalLeafPagesAllocatedBytes   *prometheus.Desc
-	PhysicalLeafPagesInUseBytes       *prometheus.Desc
-	Buckets                           *prometheus.Desc
-	InlinedBuckets                    *prometheus.Desc
-	InlinedBucketsInUseBytes          *prometheus.Desc
+	forEach func(ctx context.Context, fn forEachBucketStatsFunc) error
+	now     func() time.Time
+
+	// changes tracks the state of each bucket across collections, and is
+	// protected by the mutex of the collector which owns the
+	// bucketStatsCollector.
+	changes map[string]*bucketChange
+
+	filter metricFilter
+	descs  map[*metricDef]*prometheus.Desc
 }
 
 // newBucketStatsCollector creates a new bucketStatsCollector with the specified
-// name and forEachBucketFunc for retrieving statistics.
-func newBucketStatsCollector(name string, db *bolt.DB) *bucketStatsCollector {
-	const (
-		subsystem = "bucket"
-	)
-
-	var (
-		labels = []string{"database", "bucket"}
-	)
+// name, Bolt database handle, number of workers used to compute bucket
+// statistics, filter for enabled metrics, and metric naming.
+func newBucketStatsCollector(name string, db *bolt.DB, workers int, f metricFilter, n Naming) *bucketStatsCollector {
+	// No metrics are described when the bucket walk is skipped.
+	var defs []*metricDef
+	if f.bucketsEnabled() {
+		defs = f.filter(bucketMetricDefs())
+	}
 
 	return &bucketStatsCollector{
 		name: name,
 		db:   db,
 		// By default, forEach iterates each bucket retrieved from the Bolt
 		// database handle, but this is swappable for tests
-		forEach: forEachWithBoltDB(db),
-
-		LogicalBranchPages: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "logical_branch_pages"),
-			"Number of logical branch pages for a bucket.",
-			labels,
-			nil,
-		),
-
-		PhysicalBranchOverflowPages: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "physical_branch_overflow_pages"),
-			"Number of physical branch overflow pages for a bucket.",
-			labels,
-			nil,
-		),
-
-		LogicalLeafPages: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "logical_leaf_pages"),
-			"Number of logical leaf pages for a bucket.",
-			labels,
-			nil,
-		),
-
-		PhysicalLeafOverflowPages: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "physical_leaf_overflow_pages"),
-			"Number of physical leaf overflow pages for a bucket.",
-			labels,
-			nil,
-		),
-
-		Keys: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "keys"),
-			"Number of key/value pairs in a bucket.",
-			labels,
-			nil,
-		),
-
-		Depth: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "depth"),
-			"Number of levels in B+ tree for a bucket.",
-			labels,
-			nil,
-		),
-
-		PhysicalBranchPagesAllocatedBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "physical_branch_pages_allocated_bytes"),
-			"Number of bytes allocated in physical branch pages for a bucket.",
-			labels,
-			nil,
-		),
-
-		PhysicalBranchPagesInUseBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "physical_branch_pages_in_use_bytes"),
-			"Number of bytes in use in physical branch pages for a bucket.",
-			labels,
-			nil,
-		),
-
-		PhysicalLeafPagesAllocatedBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "physical_leaf_pages_allocated_bytes"),
-			"Number of bytes allocated in physical leaf pages for a bucket.",
-			labels,
-			nil,
-		),
-
-		PhysicalLeafPagesInUseBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "physical_leaf_pages_in_use_bytes"),
-			"Number of bytes in use in physical leaf pages for a bucket.",
-			labels,
-			nil,
-		),
-
-		Buckets: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "buckets"),
-			"Number of buckets within a bucket, including the top bucket.",
-			labels,
-			nil,
-		),
-
-		InlinedBuckets: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "inlined_buckets"),
-			"Number of inlined buckets for a bucket.",
-			labels,
-			nil,
-		),
-
-		InlinedBucketsInUseBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, subsystem, "inlined_buckets_in_use_bytes"),
-			"Number of bytes in use for inlined buckets.",
-			labels,
-			nil,
-		),
+		forEach: forEachWithBoltDB(name, db, workers),
+		now:     time.Now,
+		changes: make(map[string]*bucketChange),
+		filter:  f,
+		descs:   newPromDescs(n, defs...),
 	}
 }
 
 // Describe implements the prometheus.Collector interface.
 func (c *bucketStatsCollector) Describe(ch chan<- *prometheus.Desc) {
-	ds := []*prometheus.Desc{
-		c.LogicalBranchPages,
-		c.PhysicalBranchOverflowPages,
-		c.LogicalLeafPages,
-		c.PhysicalLeafOverflowPages,
-		c.Keys,
-		c.Depth,
-		c.PhysicalBranchPagesAllocatedBytes,
-		c.PhysicalBranchPagesInUseBytes,
-		c.PhysicalLeafPagesAllocatedBytes,
-		c.PhysicalLeafPagesInUseBytes,
-		c.Buckets,
-		c.InlinedBuckets,
-		c.InlinedBucketsInUseBytes,
-	}
-
-	for _, d := range ds {
+	for _, d := range c.descs {
 		ch <- d
 	}
 }
 
+// A bucketInfo contains statistics and metadata for a single Bolt bucket.
+type bucketInfo struct {
+	Name     string
+	Stats    bolt.BucketStats
+	Sequence uint64
+	Root     uint64
+}
+
 // A forEachBucketStatsFunc is a function which is repeatedly called for all
 // buckets in a Bolt database to collect bucket statistics.
-type forEachBucketStatsFunc func(bucket string, s bolt.BucketStats) error
+type forEachBucketStatsFunc func(b bucketInfo) error
 
 // forEachWithBoltDB begins a read-only bolt transaction and returns a forEach
 // function for a bucketStatsCollector.  The returned function is invoked
 // repeatedly for each bucket and its stats retrieved from the Bolt database
 // handle.
-func forEachWithBoltDB(db *bolt.DB) func(forEachBucketStatsFunc) error {
-	return func(iter forEachBucketStatsFunc) error {
-		return db.View(func(tx *bolt.Tx) error {
-			return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
-				// TODO(mdlayher): if/when possible, iterate child buckets and
-				// collect metrics for them as well.
-				// See: https://github.com/boltdb/bolt/issues/603.
-				return iter(string(name), b.Stats())
-			})
+//
+// Statistics for up to workers buckets are computed concurrently within the
+// same transaction, but the returned function is always invoked in the order
+// in which Bolt returns each bucket.
+//
+// If ctx is canceled, no further buckets are walked, the returned function is
+// invoked for each bucket already walked, and ctx.Err() is returned.
+//
+// The walk runs with the same runtime/pprof labels and runtime/trace region
+// as a transaction performed using DB, with the database name and the
+// operation name "prombolt_bucket_stats".
+func forEachWithBoltDB(name string, db *bolt.DB, workers int) func(context.Context, forEachBucketStatsFunc) error {
+	return func(ctx context.Context, iter forEachBucketStatsFunc) error {
+		var err error
+		profileTx(ctx, name, opBucketStats, false, "bolt.View", func() {
+			err = walkBuckets(ctx, db, workers, iter)
 		})
+
+		return err
 	}
 }
 
-// Collect implements the prometheus.Collector interface.
-func (c *bucketStatsCollector) Collect(ch chan<- prometheus.Metric) {
-	err := c.forEach(func(bucket string, s bolt.BucketStats) error {
-		ch <- prometheus.MustNewConstMetric(
-			c.LogicalBranchPages,
-			prometheus.GaugeValue,
-			float64(s.BranchPageN),
-			c.name,
-			bucket,
+// walkBuckets walks the buckets of db within a read-only transaction, as
+// described by forEachWithBoltDB.
+func walkBuckets(ctx context.Context, db *bolt.DB, workers int, iter forEachBucketStatsFunc) error {
+	return db.View(func(tx *bolt.Tx) error {
+		var (
+			infos   []bucketInfo
+			buckets []*bolt.Bucket
 		)
 
-		ch <- prometheus.MustNewConstMetric(
-			c.PhysicalBranchOverflowPages,
-			prometheus.GaugeValue,
-			float64(s.BranchOverflowN),
-			c.name,
-			bucket,
-		)
+		// TODO(mdlayher): if/when possible, iterate child buckets and
+		// collect metrics for them as well.
+		// See: https://github.com/boltdb/bolt/issues/603.
+		err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			infos = append(infos, bucketInfo{
+				Name:     bucketLabel(name),
+				Sequence: b.Sequence(),
+				Root:     uint64(b.Root()),
+			})
+			buckets = append(buckets, b)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
 
-		ch <- prometheus.MustNewConstMetric(
-			c.LogicalLeafPages,
-			prometheus.GaugeValue,
-			float64(s.LeafPageN),
-			c.name,
-			bucket,
-		)
+		n := bucketStats(ctx, infos, buckets, workers)
+		for i := 0; i < n; i++ {
+			if err := iter(infos[i]); err != nil {
+				return err
+			}
+		}
 
-		ch <- prometheus.MustNewConstMetric(
-			c.PhysicalLeafOverflowPages,
-			prometheus.GaugeValue,
-			float64(s.LeafOverflowN),
-			c.name,
-			bucket,
-		)
+		if n < len(buckets) {
+			return ctx.Err()
+		}
 
-		ch <- prometheus.MustNewConstMetric(
-			c.Keys,
-			prometheus.GaugeValue,
-			float64(s.KeyN),
-			c.name,
-			bucket,
-		)
+		return nil
+	})
+}
 
-		ch <- prometheus.MustNewConstMetric(
-			c.Depth,
-			prometheus.GaugeValue,
-			float64(s.Depth),
-			c.name,
-			bucket,
-		)
+// bucketStats computes statistics for each input bucket using up to workers
+// goroutines, and stores them in the bucketInfo at the same index.  Buckets
+// opened by a read-only transaction may safely be read concurrently.
+//
+// bucketStats returns the number of buckets walked, which is less than the
+// number of input buckets only if ctx is canceled.  The goroutines inherit the
+// runtime/pprof labels of the calling goroutine.
+func bucketStats(ctx context.Context, infos []bucketInfo, buckets []*bolt.Bucket, workers int) int {
+	if workers > len(buckets) {
+		workers = len(buckets)
+	}
 
-		ch <- prometheus.MustNewConstMetric(
-			c.PhysicalBranchPagesAllocatedBytes,
-			prometheus.GaugeValue,
-			float64(s.BranchAlloc),
-			c.name,
-			bucket,
-		)
+	idxC := make(chan int)
 
-		ch <- prometheus.MustNewConstMetric(
-			c.PhysicalBranchPagesInUseBytes,
-			prometheus.GaugeValue,
-			float64(s.BranchInuse),
-			c.name,
-			bucket,
-		)
+	var wg sync.WaitGroup
+	wg.Add(workers)
 
-		ch <- prometheus.MustNewConstMetric(
-			c.PhysicalLeafPagesAllocatedBytes,
-			prometheus.GaugeValue,
-			float64(s.LeafAlloc),
-			c.name,
-			bucket,
-		)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
 
-		ch <- prometheus.MustNewConstMetric(
-			c.PhysicalLeafPagesInUseBytes,
-			prometheus.GaugeValue,
-			float64(s.LeafInuse),
-			c.name,
-			bucket,
-		)
+			for idx := range idxC {
+				trace.WithRegion(ctx, "bolt.(*Bucket).Stats", func() {
+					infos[idx].Stats = buckets[idx].Stats()
+				})
+			}
+		}()
+	}
 
-		ch <- prometheus.MustNewConstMetric(
-			c.Buckets,
-			prometheus.GaugeValue,
-			float64(s.BucketN),
-			c.name,
-			bucket,
-		)
+	// Hand out buckets in order so that, if ctx is canceled, the buckets
+	// walked so far are a prefix of the input buckets.
+	n := 0
+dispatch:
+	for ; n < len(buckets) && ctx.Err() == nil; n++ {
+		select {
+		case idxC <- n:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(idxC)
 
-		ch <- prometheus.MustNewConstMetric(
-			c.InlinedBuckets,
-			prometheus.GaugeValue,
-			float64(s.InlineBucketN),
-			c.name,
-			bucket,
-		)
+	wg.Wait()
+	return n
+}
 
-		ch <- prometheus.MustNewConstMetric(
-			c.InlinedBucketsInUseBytes,
-			prometheus.GaugeValue,
-			float64(s.InlineBucketInuse),
-			c.name,
-			bucket,
-		)
+// Collect implements the prometheus.Collector interface.
+func (c *bucketStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.gather(context.Background(), &promSink{
+		ch:    ch,
+		name:  c.name,
+		descs: c.descs,
+	})
+}
+
+// gather sends the values of the metrics defined by bucketDefs and
+// scrapeTruncatedDef which are enabled to sink, until all buckets are walked,
+// or ctx is canceled.
+func (c *bucketStatsCollector) gather(ctx context.Context, sink metricSink) {
+	gatherBuckets(ctx, sink, c.filter, c)
+}
+
+// walk invokes fn for each bucket walked, along with the tracked changes for
+// the bucket, until all buckets are walked or ctx is canceled.
+func (c *bucketStatsCollector) walk(ctx context.Context, fn func(b bucketInfo, bc *bucketChange)) error {
+	now := c.now()
+	seen := make(map[string]struct{})
 
+	err := c.forEach(ctx, func(b bucketInfo) error {
+		fn(b, c.observeChange(b, now))
+		seen[b.Name] = struct{}{}
 		return nil
 	})
 	if err != nil {
-		ch <- prometheus.NewInvalidMetric(c.Buckets, err)
+		return err
+	}
+
+	// Only forget buckets which no longer exist after a complete walk.
+	for name := range c.changes {
+		if _, ok := seen[name]; !ok {
+			delete(c.changes, name)
+		}
 	}
+
+	return nil
+}
+
+// A bucketChange tracks changes to a bucket across metrics collections.
+type bucketChange struct {
+	root  uint64
+	seq   uint64
+	stats bolt.BucketStats
+
+	last  time.Time
+	total int
+}
+
+// observeChange compares b with the state of the same bucket in the previous
+// collection, records b's state, and returns the tracked changes for b.
+//
+// Because Bolt is copy-on-write, any write to a bucket allocates a new root
+// page for it.  Inlined buckets have no root page, so their statistics are
+// compared instead.
+func (c *bucketStatsCollector) observeChange(b bucketInfo, now time.Time) *bucketChange {
+	bc, ok := c.changes[b.Name]
+	if !ok {
+		// No previous state to compare against.
+		bc = &bucketChange{last: now}
+		c.changes[b.Name] = bc
+	} else if bc.root != b.Root || bc.seq != b.Sequence || (b.Root == 0 && bc.stats != b.Stats) {
+		bc.last = now
+		bc.total++
+	}
+
+	bc.root = b.Root
+	bc.seq = b.Sequence
+	bc.stats = b.Stats
+
+	return bc
 }
@@ -1,11 +1,14 @@
 package prombolt
 
 import (
+	"context"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/boltdb/bolt"
-	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestBucketStatsCollector(t *testing.T) {
@@ -33,6 +36,7 @@ func TestBucketStatsCollector(t *testing.T) {
 					InlineBucketN:     12,
 					InlineBucketInuse: 13,
 				},
+				seq: 14,
 			}},
 			matches: []string{
 				`bolt_bucket_logical_branch_pages{bucket="foo",database="test.db"} 1`,
@@ -48,6 +52,8 @@ func TestBucketStatsCollector(t *testing.T) {
 				`bolt_bucket_buckets{bucket="foo",database="test.db"} 11`,
 				`bolt_bucket_inlined_buckets{bucket="foo",database="test.db"} 12`,
 				`bolt_bucket_inlined_buckets_in_use_bytes{bucket="foo",database="test.db"} 13`,
+				`bolt_bucket_sequence{bucket="foo",database="test.db"} 14`,
+				`bolt_scrape_truncated{database="test.db"} 0`,
 			},
 		},
 		{
@@ -136,17 +142,277 @@ func TestBucketStatsCollector(t *testing.T) {
 	}
 }
 
+func TestBucketStatsCollectorChanges(t *testing.T) {
+	stats := []memoryBucketStats{{
+		name: "foo",
+		root: 1,
+	}}
+
+	bs := newMemoryBucketStatsCollector(stats)
+
+	tests := []struct {
+		name    string
+		now     int64
+		change  func()
+		matches []string
+	}{
+		{
+			name: "first seen",
+			now:  10,
+			matches: []string{
+				`bolt_bucket_last_change_timestamp_seconds{bucket="foo",database="test.db"} 10`,
+				`bolt_bucket_changes_total{bucket="foo",database="test.db"} 0`,
+			},
+		},
+		{
+			name: "root changed",
+			now:  20,
+			change: func() {
+				stats[0].root = 2
+			},
+			matches: []string{
+				`bolt_bucket_last_change_timestamp_seconds{bucket="foo",database="test.db"} 20`,
+				`bolt_bucket_changes_total{bucket="foo",database="test.db"} 1`,
+			},
+		},
+		{
+			name: "unchanged",
+			now:  30,
+			matches: []string{
+				`bolt_bucket_last_change_timestamp_seconds{bucket="foo",database="test.db"} 20`,
+				`bolt_bucket_changes_total{bucket="foo",database="test.db"} 1`,
+			},
+		},
+		{
+			name: "sequence changed",
+			now:  40,
+			change: func() {
+				stats[0].seq = 1
+			},
+			matches: []string{
+				`bolt_bucket_last_change_timestamp_seconds{bucket="foo",database="test.db"} 40`,
+				`bolt_bucket_changes_total{bucket="foo",database="test.db"} 2`,
+			},
+		},
+		{
+			name: "inlined bucket stats changed",
+			now:  50,
+			change: func() {
+				stats[0].root = 0
+				stats[0].s.KeyN = 1
+			},
+			matches: []string{
+				`bolt_bucket_last_change_timestamp_seconds{bucket="foo",database="test.db"} 50`,
+				`bolt_bucket_changes_total{bucket="foo",database="test.db"} 3`,
+			},
+		},
+	}
+
+	// Each test case depends on the state left by the previous one.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.change != nil {
+				tt.change()
+			}
+
+			bs.now = func() time.Time {
+				return time.Unix(tt.now, 0)
+			}
+
+			got := testCollector(t, bs)
+
+			for _, m := range tt.matches {
+				if !strings.Contains(got, m) {
+					t.Fatalf("output did not contain expected metric: %q", m)
+				}
+			}
+		})
+	}
+}
+
+func TestForEachWithBoltDBWorkers(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		for i := 0; i < 16; i++ {
+			b, err := tx.CreateBucket([]byte(fmt.Sprintf("bucket%02d", i)))
+			if err != nil {
+				return err
+			}
+
+			for j := 0; j < i*64; j++ {
+				if err := b.Put([]byte(fmt.Sprintf("key%04d", j)), []byte("value")); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to populate database: %v", err)
+	}
+
+	collect := func(workers int) ([]string, []bolt.BucketStats) {
+		var (
+			names []string
+			stats []bolt.BucketStats
+		)
+
+		err := forEachWithBoltDB("test.db", db, workers)(context.Background(), func(b bucketInfo) error {
+			names = append(names, b.Name)
+			stats = append(stats, b.Stats)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to iterate buckets: %v", err)
+		}
+
+		return names, stats
+	}
+
+	wantNames, wantStats := collect(1)
+	if want, got := 16, len(wantNames); want != got {
+		t.Fatalf("unexpected number of buckets:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	for _, workers := range []int{2, 4, 32} {
+		t.Run(fmt.Sprintf("workers %d", workers), func(t *testing.T) {
+			names, stats := collect(workers)
+
+			if want, got := wantNames, names; !reflect.DeepEqual(want, got) {
+				t.Fatalf("unexpected bucket names:\n- want: %v\n-  got: %v", want, got)
+			}
+			if want, got := wantStats, stats; !reflect.DeepEqual(want, got) {
+				t.Fatalf("unexpected bucket stats:\n- want: %v\n-  got: %v", want, got)
+			}
+		})
+	}
+}
+
+func TestForEachWithBoltDBSequence(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("foo"))
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < 3; i++ {
+			if _, err := b.NextSequence(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to populate database: %v", err)
+	}
+
+	var seq uint64
+	err = forEachWithBoltDB("test.db", db, 1)(context.Background(), func(b bucketInfo) error {
+		seq = b.Sequence
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to iterate buckets: %v", err)
+	}
+
+	if want, got := uint64(3), seq; want != got {
+		t.Fatalf("unexpected bucket sequence:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestBucketStatsCollectorDistinctLabels(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	// Each of these buckets must produce its own series, or the collection
+	// fails due to duplicate series.
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{{0xff}, []byte("0xff"), []byte(`"\xff"`)} {
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to populate database: %v", err)
+	}
+
+	got := testCollector(t, newBucketStatsCollector("test.db", db, 1, metricFilter{}, Naming{}))
+
+	matches := []string{
+		`bolt_bucket_keys{bucket="0xff",database="test.db"} 0`,
+		`bolt_bucket_keys{bucket="\"\\xff\"",database="test.db"} 0`,
+		`bolt_bucket_keys{bucket="\"\\\"\\\\xff\\\"\"",database="test.db"} 0`,
+	}
+
+	for _, m := range matches {
+		if !strings.Contains(got, m) {
+			t.Fatalf("output did not contain expected metric: %q\n%s", m, got)
+		}
+	}
+}
+
+func TestForEachWithBoltDBCanceled(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("foo"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to populate database: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var n int
+	err = forEachWithBoltDB("test.db", db, 1)(ctx, func(_ bucketInfo) error {
+		n++
+		return nil
+	})
+
+	if want, got := context.Canceled, err; want != got {
+		t.Fatalf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+	}
+	if want, got := 0, n; want != got {
+		t.Fatalf("unexpected number of buckets walked:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
 type memoryBucketStats struct {
 	name string
 	s    bolt.BucketStats
+	seq  uint64
+	root uint64
 }
 
-func newMemoryBucketStatsCollector(stats []memoryBucketStats) prometheus.Collector {
-	bs := newBucketStatsCollector("test.db", nil)
+func newMemoryBucketStatsCollector(stats []memoryBucketStats) *bucketStatsCollector {
+	bs := newBucketStatsCollector("test.db", nil, 1, metricFilter{}, Naming{})
 
-	bs.forEach = func(fn forEachBucketStatsFunc) error {
+	bs.forEach = func(ctx context.Context, fn forEachBucketStatsFunc) error {
 		for _, s := range stats {
-			if err := fn(s.name, s.s); err != nil {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			err := fn(bucketInfo{
+				Name:     s.name,
+				Stats:    s.s,
+				Sequence: s.seq,
+				Root:     s.root,
+			})
+			if err != nil {
 				return err
 			}
 		}
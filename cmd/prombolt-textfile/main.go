@@ -0,0 +1,95 @@
+// Command prombolt-textfile writes metrics for a Bolt database to a file for
+// the node_exporter textfile collector.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/mdlayher/prombolt"
+)
+
+func main() {
+	var (
+		dbFlag       = flag.String("db", "", "path to the Bolt database")
+		nameFlag     = flag.String("name", "", "name of the database used as a label (default: base name of -db)")
+		outFlag      = flag.String("out", "", "path to the output file, typically in the textfile collector directory with a .prom extension")
+		intervalFlag = flag.Duration("interval", 0, "if set, write metrics periodically at this interval rather than once, opening the database only for each write")
+		workersFlag  = flag.Int("workers", 1, "number of goroutines used to compute bucket statistics")
+		timeoutFlag  = flag.Duration("timeout", 0, "if set, deadline for each collection of bucket statistics")
+	)
+
+	flag.Parse()
+
+	if *dbFlag == "" || *outFlag == "" {
+		log.Fatal("both -db and -out must be specified")
+	}
+
+	name := *nameFlag
+	if name == "" {
+		name = filepath.Base(*dbFlag)
+	}
+
+	options := []prombolt.Option{
+		prombolt.Workers(*workersFlag),
+		prombolt.Timeout(*timeoutFlag),
+	}
+
+	if *intervalFlag <= 0 {
+		if err := writeTextfile(*dbFlag, *outFlag, name, options...); err != nil {
+			log.Fatalf("failed to write metrics: %v", err)
+		}
+
+		return
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	t := time.NewTicker(*intervalFlag)
+	defer t.Stop()
+
+	for {
+		// Keep writing on later ticks if the database is temporarily
+		// unavailable, such as while the owning application restarts.
+		if err := writeTextfile(*dbFlag, *outFlag, name, options...); err != nil {
+			log.Printf("failed to write metrics: %v", err)
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// writeTextfile opens the database at path, writes its metrics to out, and
+// closes it again.
+//
+// The database is only held open while metrics are written, since an open
+// read-only handle holds a shared lock on the file which blocks the owning
+// application from opening it.  As a result, bucket changes are not tracked
+// between writes.  Long-running applications should use
+// prombolt.WriteTextfileEvery with their own database handle instead.
+func writeTextfile(path, out, name string, options ...prombolt.Option) error {
+	// Open the database read-only so that other processes may do the same,
+	// and give up rather than wait indefinitely for a writer to close it.
+	db, err := bolt.Open(path, 0600, &bolt.Options{
+		ReadOnly: true,
+		Timeout:  5 * time.Second,
+	})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return prombolt.WriteTextfile(out, name, db, options...)
+}
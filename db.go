@@ -0,0 +1,594 @@
+package prombolt
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ prometheus.Collector = &DB{}
+
+// A DB wraps a Bolt database handle, and records Prometheus metrics for each
+// transaction performed using its Update, Batch, and View methods, or their
+// context-aware equivalents.
+//
+// DB is a prometheus.Collector, and is registered with Prometheus separately
+// from the collector returned by New.  All methods of the embedded *bolt.DB
+// which are not overridden by DB do not record metrics.
+type DB struct {
+	*bolt.DB
+	name string
+
+	// ops is the set of operation names used as label values.  If opsFixed
+	// is true, no further names are added.
+	opsMu    sync.Mutex
+	ops      map[string]struct{}
+	opsFixed bool
+
+	// slowHook is called for transactions which take at least slowThreshold.
+	slowThreshold time.Duration
+	slowHook      func(SlowTx)
+
+	tracer trace.Tracer
+
+	// writeTx is the state of the most recent write transaction begun by
+	// Update or Batch.  Bolt only permits a single write transaction at a
+	// time.
+	writeTxMu sync.Mutex
+	writeTx   *writeTxState
+
+	txDuration *prometheus.HistogramVec
+
+	txPagesAllocated *prometheus.HistogramVec
+	txNodesSplit     *prometheus.HistogramVec
+	txNodesSpilled   *prometheus.HistogramVec
+	txWrites         *prometheus.HistogramVec
+	txSpillSeconds   *prometheus.HistogramVec
+
+	txWriteAmplification *prometheus.HistogramVec
+	txUserWrittenBytes   *prometheus.CounterVec
+	txDiskWrittenBytes   *prometheus.CounterVec
+
+	writeTxTotal *prometheus.CounterVec
+	txErrors     *prometheus.CounterVec
+
+	// buckets caches the metrics for operations on each bucket.  If
+	// bucketsFixed is true, only the names in bucketNames are used as label
+	// values.
+	bucketMu     sync.Mutex
+	buckets      map[string]*bucketMetrics
+	bucketNames  map[string]struct{}
+	bucketsFixed bool
+
+	bucketOps          *prometheus.CounterVec
+	bucketWrittenBytes *prometheus.CounterVec
+}
+
+// NewDB wraps a Bolt database handle to create a DB.
+//
+// Name should specify a unique name for the database, and will be added
+// as a label to all produced Prometheus metrics.  Typically, it should be
+// the same name passed to New.
+//
+// Zero or more DBOptions may be specified to configure the DB.
+func NewDB(name string, db *bolt.DB, options ...DBOption) *DB {
+	const (
+		subsystem = "write_tx"
+	)
+
+	cfg := &dbConfig{}
+	for _, o := range options {
+		o(cfg)
+	}
+
+	ops := make(map[string]struct{}, len(cfg.ops))
+	for _, op := range cfg.ops {
+		ops[op] = struct{}{}
+	}
+
+	bucketNames := make(map[string]struct{}, len(cfg.buckets))
+	for _, b := range cfg.buckets {
+		bucketNames[b] = struct{}{}
+	}
+
+	var (
+		n = cfg.naming
+
+		labels   = []string{"database"}
+		opLabels = []string{"database", "operation"}
+
+		// Most write transactions touch few pages and nodes, but the purpose
+		// of these metrics is to find those which touch many.
+		countBuckets = prometheus.ExponentialBuckets(1, 2, 16)
+	)
+
+	return &DB{
+		DB:   db,
+		name: name,
+
+		ops:      ops,
+		opsFixed: len(cfg.ops) > 0,
+
+		slowThreshold: cfg.slowThreshold,
+		slowHook:      cfg.slowHook,
+
+		tracer: newTracer(cfg.tracerProvider),
+
+		txDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem("tx"),
+				ConstLabels: n.ConstLabels,
+				Name:        "duration_seconds",
+				Help:        "Distribution of the amount of time in seconds taken by each transaction, including waiting for the transaction to begin.",
+				Buckets:     prometheus.ExponentialBuckets(0.0001, 2, 16),
+			},
+			[]string{"database", "operation", "rw"},
+		),
+
+		txPagesAllocated: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem(subsystem),
+				ConstLabels: n.ConstLabels,
+				Name:        "pages_allocated",
+				Help:        "Distribution of the number of pages allocated by each committed write transaction.",
+				Buckets:     countBuckets,
+			},
+			opLabels,
+		),
+
+		txNodesSplit: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem(subsystem),
+				ConstLabels: n.ConstLabels,
+				Name:        "nodes_split",
+				Help:        "Distribution of the number of nodes split by each committed write transaction.",
+				Buckets:     countBuckets,
+			},
+			opLabels,
+		),
+
+		txNodesSpilled: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem(subsystem),
+				ConstLabels: n.ConstLabels,
+				Name:        "nodes_spilled",
+				Help:        "Distribution of the number of nodes spilled by each committed write transaction.",
+				Buckets:     countBuckets,
+			},
+			opLabels,
+		),
+
+		txWrites: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem(subsystem),
+				ConstLabels: n.ConstLabels,
+				Name:        "writes",
+				Help:        "Distribution of the number of writes to disk performed by each committed write transaction.",
+				Buckets:     countBuckets,
+			},
+			opLabels,
+		),
+
+		txSpillSeconds: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem(subsystem),
+				ConstLabels: n.ConstLabels,
+				Name:        "spill_seconds",
+				Help:        "Distribution of the amount of time in seconds spent spilling nodes by each committed write transaction.",
+				Buckets:     prometheus.ExponentialBuckets(0.0001, 2, 16),
+			},
+			opLabels,
+		),
+
+		txWriteAmplification: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem(subsystem),
+				ConstLabels: n.ConstLabels,
+				Name:        "write_amplification",
+				Help:        "Distribution of the estimated ratio of bytes written to disk to key and value bytes written using a Bucket, for each committed write transaction which wrote keys or values.",
+				Buckets:     prometheus.ExponentialBuckets(1, 2, 16),
+			},
+			opLabels,
+		),
+
+		txUserWrittenBytes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem(subsystem),
+				ConstLabels: n.ConstLabels,
+				Name:        "user_written_bytes_total",
+				Help:        "Total number of key and value bytes written using a Bucket by committed write transactions.",
+			},
+			labels,
+		),
+
+		txDiskWrittenBytes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem(subsystem),
+				ConstLabels: n.ConstLabels,
+				Name:        "disk_written_bytes_total",
+				Help:        "Estimated total number of bytes written to disk by committed write transactions, computed as the number of writes multiplied by the page size.",
+			},
+			labels,
+		),
+
+		writeTxTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem(subsystem),
+				ConstLabels: n.ConstLabels,
+				Name:        "total",
+				Help:        "Total number of write transactions by outcome: committed, rolled back due to an error returned by the transaction function, or failed due to a Bolt error.",
+			},
+			[]string{"database", "outcome"},
+		),
+
+		txErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem("tx"),
+				ConstLabels: n.ConstLabels,
+				Name:        "errors_total",
+				Help:        "Total number of errors returned by transactions, classified by Bolt error.",
+			},
+			[]string{"database", "error"},
+		),
+
+		buckets:      make(map[string]*bucketMetrics),
+		bucketNames:  bucketNames,
+		bucketsFixed: len(cfg.buckets) > 0,
+
+		bucketOps: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem("bucket"),
+				ConstLabels: n.ConstLabels,
+				Name:        "operations_total",
+				Help:        "Total number of operations performed on a bucket using a Tx, Bucket, or Cursor.",
+			},
+			[]string{"database", "bucket", "op"},
+		),
+
+		bucketWrittenBytes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   n.namespace(),
+				Subsystem:   n.subsystem("bucket"),
+				ConstLabels: n.ConstLabels,
+				Name:        "written_bytes_total",
+				Help:        "Total number of key and value bytes written to a bucket using a Bucket.",
+			},
+			[]string{"database", "bucket"},
+		),
+	}
+}
+
+// Update executes a function within the context of a read-write managed
+// transaction, as with (*bolt.DB).Update.  If the transaction is committed,
+// its statistics are recorded.
+func (db *DB) Update(fn func(*bolt.Tx) error) error {
+	return db.UpdateContext(context.Background(), fn)
+}
+
+// UpdateContext is like Update, but metrics for the transaction are labeled
+// with the operation name attached to ctx using WithOp.  If ctx is canceled
+// before the transaction begins, UpdateContext returns ctx.Err().
+//
+// The transaction runs with runtime/pprof labels for the database, operation,
+// and rw, and within a runtime/trace region, so that profiles and execution
+// traces attribute its cost to the operation.
+func (db *DB) UpdateContext(ctx context.Context, fn func(*bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	op := db.operation(ctx)
+	start := time.Now()
+
+	ctx, span := db.startSpan(ctx, "bolt.Update", op, true)
+
+	var (
+		btx      *bolt.Tx
+		fnFailed bool
+		err      error
+	)
+	profileTx(ctx, db.name, op, true, "bolt.Update", func() {
+		err = db.DB.Update(func(tx *bolt.Tx) error {
+			db.beginWriteTx(tx, op)
+			btx = tx
+			fnErr := fn(tx)
+			fnFailed = fnErr != nil
+			return fnErr
+		})
+	})
+
+	db.endSpan(span, btx, err)
+	db.observeTx(op, true, start, btx)
+	db.observeWriteTx(err, fnFailed)
+	return err
+}
+
+// Batch calls fn as part of a batch, as with (*bolt.DB).Batch.  If the
+// transaction used for the batch is committed, its statistics are recorded
+// once for all calls in the batch.
+func (db *DB) Batch(fn func(*bolt.Tx) error) error {
+	return db.BatchContext(context.Background(), fn)
+}
+
+// BatchContext is like Batch, but metrics for the transaction are labeled
+// with the operation name attached to ctx using WithOp.  The statistics for a
+// transaction shared by several calls are labeled with the operation name of
+// the first call.  If ctx is canceled before fn is added to a batch,
+// BatchContext returns ctx.Err().
+//
+// As with UpdateContext, fn runs with runtime/pprof labels and within a
+// runtime/trace region, but the commit of a batch is not attributed to any
+// single operation.
+func (db *DB) BatchContext(ctx context.Context, fn func(*bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	op := db.operation(ctx)
+	start := time.Now()
+
+	ctx, span := db.startSpan(ctx, "bolt.Batch", op, true)
+
+	var (
+		btx      *bolt.Tx
+		fnFailed bool
+	)
+	err := db.DB.Batch(func(tx *bolt.Tx) error {
+		db.beginWriteTx(tx, op)
+		btx = tx
+
+		// Bolt runs batches on its own goroutine, so only the time spent in
+		// fn can be attributed to the caller.
+		var fnErr error
+		profileTx(ctx, db.name, op, true, "bolt.Batch", func() {
+			fnErr = fn(tx)
+		})
+		fnFailed = fnErr != nil
+		return fnErr
+	})
+
+	db.endSpan(span, btx, err)
+	db.observeTx(op, true, start, btx)
+	db.observeWriteTx(err, fnFailed)
+	return err
+}
+
+// View executes a function within the context of a managed read-only
+// transaction, as with (*bolt.DB).View.
+func (db *DB) View(fn func(*bolt.Tx) error) error {
+	return db.ViewContext(context.Background(), fn)
+}
+
+// ViewContext is like View, but metrics for the transaction are labeled with
+// the operation name attached to ctx using WithOp.  If ctx is canceled before
+// the transaction begins, ViewContext returns ctx.Err().
+//
+// As with UpdateContext, the transaction runs with runtime/pprof labels and
+// within a runtime/trace region.
+func (db *DB) ViewContext(ctx context.Context, fn func(*bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	op := db.operation(ctx)
+	start := time.Now()
+
+	ctx, span := db.startSpan(ctx, "bolt.View", op, false)
+
+	var (
+		btx *bolt.Tx
+		err error
+	)
+	profileTx(ctx, db.name, op, false, "bolt.View", func() {
+		err = db.DB.View(func(tx *bolt.Tx) error {
+			btx = tx
+			return fn(tx)
+		})
+	})
+
+	db.endSpan(span, btx, err)
+	db.observeTx(op, false, start, btx)
+	db.observeErr(err)
+	return err
+}
+
+// observeTx records the duration of the transaction tx which began at start,
+// and reports it if it is slow.  tx is nil if the transaction could not be
+// begun.
+func (db *DB) observeTx(op string, rw bool, start time.Time, tx *bolt.Tx) {
+	d := time.Since(start)
+	db.txDuration.WithLabelValues(db.name, op, strconv.FormatBool(rw)).Observe(d.Seconds())
+
+	if db.slowThreshold > 0 && d >= db.slowThreshold {
+		db.reportSlowTx(d, op, rw, tx)
+	}
+}
+
+// A writeTxState is the state of a single write transaction.
+type writeTxState struct {
+	tx       *bolt.Tx
+	op       string
+	pageSize int
+
+	// userBytes is the number of key and value bytes written by the
+	// transaction using a Bucket.  Bolt transactions must not be used
+	// concurrently, so no synchronization is needed.
+	userBytes int
+}
+
+// beginWriteTx begins tracking the state of the write transaction tx for the
+// operation op, and records its statistics if it is committed.  beginWriteTx
+// may be called several times for the same transaction, as it is shared by
+// the calls which make up a batch, but the transaction is only tracked once.
+func (db *DB) beginWriteTx(tx *bolt.Tx, op string) {
+	db.writeTxMu.Lock()
+	defer db.writeTxMu.Unlock()
+
+	if db.writeTx != nil && db.writeTx.tx == tx {
+		return
+	}
+
+	s := &writeTxState{
+		tx:       tx,
+		op:       op,
+		pageSize: tx.DB().Info().PageSize,
+	}
+	db.writeTx = s
+
+	// Commit handlers run after the transaction is fully written, so its
+	// statistics are complete.
+	tx.OnCommit(func() {
+		db.observeTxStats(tx.Stats(), s)
+	})
+}
+
+// trackedWriteTx returns the state of tx if it is the write transaction being
+// tracked by db, or nil otherwise.
+func (db *DB) trackedWriteTx(tx *bolt.Tx) *writeTxState {
+	db.writeTxMu.Lock()
+	defer db.writeTxMu.Unlock()
+
+	if db.writeTx != nil && db.writeTx.tx == tx {
+		return db.writeTx
+	}
+
+	return nil
+}
+
+// observeTxStats records the statistics of a single committed write
+// transaction.
+func (db *DB) observeTxStats(s bolt.TxStats, ws *writeTxState) {
+	db.txPagesAllocated.WithLabelValues(db.name, ws.op).Observe(float64(s.PageCount))
+	db.txNodesSplit.WithLabelValues(db.name, ws.op).Observe(float64(s.Split))
+	db.txNodesSpilled.WithLabelValues(db.name, ws.op).Observe(float64(s.Spill))
+	db.txWrites.WithLabelValues(db.name, ws.op).Observe(float64(s.Write))
+	db.txSpillSeconds.WithLabelValues(db.name, ws.op).Observe(s.SpillTime.Seconds())
+
+	// Bolt does not report the number of bytes written to disk, but nearly
+	// all writes are a single page, or a page and its overflow pages.
+	diskBytes := s.Write * ws.pageSize
+
+	db.txUserWrittenBytes.WithLabelValues(db.name).Add(float64(ws.userBytes))
+	db.txDiskWrittenBytes.WithLabelValues(db.name).Add(float64(diskBytes))
+
+	if ws.userBytes > 0 {
+		db.txWriteAmplification.WithLabelValues(db.name, ws.op).Observe(float64(diskBytes) / float64(ws.userBytes))
+	}
+}
+
+// observeWriteTx records the outcome of a write transaction, given the error
+// returned by Bolt and whether the transaction function returned an error.
+//
+// Errors are not compared directly, because errors of uncomparable types,
+// such as slices, cause a panic when compared.
+func (db *DB) observeWriteTx(err error, fnFailed bool) {
+	var outcome string
+	switch {
+	case err == nil:
+		outcome = "commit"
+	case fnFailed:
+		outcome = "rollback"
+	default:
+		outcome = "error"
+	}
+
+	db.writeTxTotal.WithLabelValues(db.name, outcome).Inc()
+	db.observeErr(err)
+}
+
+// observeErr records a classified error returned by a transaction, if err is
+// not nil.
+func (db *DB) observeErr(err error) {
+	if err == nil {
+		return
+	}
+
+	db.txErrors.WithLabelValues(db.name, classifyError(err)).Inc()
+}
+
+// boltErrors maps Bolt's sentinel errors to label values.
+var boltErrors = []struct {
+	err   error
+	label string
+}{
+	{err: bolt.ErrDatabaseNotOpen, label: "database_not_open"},
+	{err: bolt.ErrDatabaseOpen, label: "database_open"},
+	{err: bolt.ErrInvalid, label: "invalid"},
+	{err: bolt.ErrVersionMismatch, label: "version_mismatch"},
+	{err: bolt.ErrChecksum, label: "checksum"},
+	{err: bolt.ErrTimeout, label: "timeout"},
+	{err: bolt.ErrTxNotWritable, label: "tx_not_writable"},
+	{err: bolt.ErrTxClosed, label: "tx_closed"},
+	{err: bolt.ErrDatabaseReadOnly, label: "database_read_only"},
+	{err: bolt.ErrBucketNotFound, label: "bucket_not_found"},
+	{err: bolt.ErrBucketExists, label: "bucket_exists"},
+	{err: bolt.ErrBucketNameRequired, label: "bucket_name_required"},
+	{err: bolt.ErrKeyRequired, label: "key_required"},
+	{err: bolt.ErrKeyTooLarge, label: "key_too_large"},
+	{err: bolt.ErrValueTooLarge, label: "value_too_large"},
+	{err: bolt.ErrIncompatibleValue, label: "incompatible_value"},
+}
+
+// classifyError returns a label value for err, which is one of Bolt's
+// sentinel errors or "other".  Classifying errors keeps the set of label
+// values bounded.
+func classifyError(err error) string {
+	for _, e := range boltErrors {
+		if errors.Is(err, e.err) {
+			return e.label
+		}
+	}
+
+	return "other"
+}
+
+// collectors returns the prometheus.Collectors used by db.
+func (db *DB) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		db.txDuration,
+		db.txPagesAllocated,
+		db.txNodesSplit,
+		db.txNodesSpilled,
+		db.txWrites,
+		db.txSpillSeconds,
+		db.txWriteAmplification,
+		db.txUserWrittenBytes,
+		db.txDiskWrittenBytes,
+		db.writeTxTotal,
+		db.txErrors,
+		db.bucketOps,
+		db.bucketWrittenBytes,
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (db *DB) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range db.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements the prometheus.Collector interface.
+func (db *DB) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range db.collectors() {
+		c.Collect(ch)
+	}
+}
@@ -0,0 +1,294 @@
+package prombolt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestDBUpdate(t *testing.T) {
+	bdb, done := testDB(t)
+	defer done()
+
+	db := NewDB("test.db", bdb)
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("foo"))
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < 512; i++ {
+			if err := b.Put([]byte(fmt.Sprintf("key%04d", i)), make([]byte, 64)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+
+	// Rolled back transactions are not observed.
+	errFoo := errors.New("foo")
+	err = db.Update(func(tx *bolt.Tx) error {
+		return errFoo
+	})
+	if want, got := errFoo, err; want != got {
+		t.Fatalf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	got := testCollector(t, db)
+
+	matches := []string{
+		`bolt_write_tx_pages_allocated_count{database="test.db",operation="unknown"} 1`,
+		`bolt_write_tx_nodes_split_count{database="test.db",operation="unknown"} 1`,
+		`bolt_write_tx_nodes_spilled_count{database="test.db",operation="unknown"} 1`,
+		`bolt_write_tx_writes_count{database="test.db",operation="unknown"} 1`,
+		`bolt_write_tx_spill_seconds_count{database="test.db",operation="unknown"} 1`,
+		// A single leaf page cannot hold 512 keys of this size.
+		`bolt_write_tx_nodes_split_bucket{database="test.db",operation="unknown",le="1"} 0`,
+	}
+
+	for _, m := range matches {
+		if !strings.Contains(got, m) {
+			t.Fatalf("output did not contain expected metric: %q", m)
+		}
+	}
+}
+
+func TestDBOutcomes(t *testing.T) {
+	bdb, done := testDB(t)
+	defer done()
+
+	db := NewDB("test.db", bdb)
+
+	errFoo := errors.New("foo")
+
+	// Each function is expected to return an error only if specified.
+	fns := []struct {
+		name string
+		fn   func() error
+		err  error
+	}{
+		{
+			name: "update commit",
+			fn: func() error {
+				return db.Update(func(tx *bolt.Tx) error {
+					_, err := tx.CreateBucket([]byte("foo"))
+					return err
+				})
+			},
+		},
+		{
+			name: "batch commit",
+			fn: func() error {
+				return db.Batch(func(tx *bolt.Tx) error {
+					return tx.Bucket([]byte("foo")).Put([]byte("foo"), nil)
+				})
+			},
+		},
+		{
+			name: "update rollback other",
+			fn: func() error {
+				return db.Update(func(tx *bolt.Tx) error {
+					return errFoo
+				})
+			},
+			err: errFoo,
+		},
+		{
+			name: "batch rollback bucket not found",
+			fn: func() error {
+				return db.Batch(func(tx *bolt.Tx) error {
+					return tx.DeleteBucket([]byte("bar"))
+				})
+			},
+			err: bolt.ErrBucketNotFound,
+		},
+		{
+			name: "view tx not writable",
+			fn: func() error {
+				return db.View(func(tx *bolt.Tx) error {
+					return tx.Bucket([]byte("foo")).Put([]byte("bar"), nil)
+				})
+			},
+			err: bolt.ErrTxNotWritable,
+		},
+		{
+			name: "update database not open",
+			fn: func() error {
+				if err := bdb.Close(); err != nil {
+					return err
+				}
+
+				return db.Update(func(tx *bolt.Tx) error {
+					return nil
+				})
+			},
+			err: bolt.ErrDatabaseNotOpen,
+		},
+	}
+
+	for _, f := range fns {
+		if want, got := f.err, f.fn(); want != got {
+			t.Fatalf("%s: unexpected error:\n- want: %v\n-  got: %v", f.name, want, got)
+		}
+	}
+
+	got := testCollector(t, db)
+
+	matches := []string{
+		`bolt_write_tx_total{database="test.db",outcome="commit"} 2`,
+		`bolt_write_tx_total{database="test.db",outcome="rollback"} 2`,
+		`bolt_write_tx_total{database="test.db",outcome="error"} 1`,
+		`bolt_tx_errors_total{database="test.db",error="bucket_not_found"} 1`,
+		`bolt_tx_errors_total{database="test.db",error="database_not_open"} 1`,
+		`bolt_tx_errors_total{database="test.db",error="other"} 1`,
+		`bolt_tx_errors_total{database="test.db",error="tx_not_writable"} 1`,
+	}
+
+	for _, m := range matches {
+		if !strings.Contains(got, m) {
+			t.Fatalf("output did not contain expected metric: %q", m)
+		}
+	}
+}
+
+// A multiErr is an error of an uncomparable type.
+type multiErr []error
+
+func (e multiErr) Error() string { return fmt.Sprintf("%d errors", len(e)) }
+
+func TestDBOutcomesUncomparableError(t *testing.T) {
+	bdb, done := testDB(t)
+	defer done()
+
+	db := NewDB("test.db", bdb)
+
+	fns := []struct {
+		name string
+		fn   func(fn func(*bolt.Tx) error) error
+	}{
+		{name: "update", fn: db.Update},
+		{name: "batch", fn: db.Batch},
+	}
+
+	for _, f := range fns {
+		err := f.fn(func(tx *bolt.Tx) error {
+			return multiErr{errors.New("foo"), errors.New("bar")}
+		})
+		if _, ok := err.(multiErr); !ok {
+			t.Fatalf("%s: unexpected error: %v", f.name, err)
+		}
+	}
+
+	got := testCollector(t, db)
+
+	matches := []string{
+		`bolt_write_tx_total{database="test.db",outcome="rollback"} 2`,
+		`bolt_tx_errors_total{database="test.db",error="other"} 2`,
+	}
+
+	for _, m := range matches {
+		if !strings.Contains(got, m) {
+			t.Fatalf("output did not contain expected metric: %q", m)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err   error
+		label string
+	}{
+		{
+			err:   bolt.ErrDatabaseNotOpen,
+			label: "database_not_open",
+		},
+		{
+			err:   bolt.ErrTimeout,
+			label: "timeout",
+		},
+		{
+			err:   bolt.ErrDatabaseReadOnly,
+			label: "database_read_only",
+		},
+		{
+			err:   fmt.Errorf("wrapped: %w", bolt.ErrBucketNotFound),
+			label: "bucket_not_found",
+		},
+		{
+			err:   errors.New("foo"),
+			label: "other",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if want, got := tt.label, classifyError(tt.err); want != got {
+				t.Fatalf("unexpected label:\n- want: %v\n-  got: %v", want, got)
+			}
+		})
+	}
+}
+
+func TestDBBatch(t *testing.T) {
+	bdb, done := testDB(t)
+	defer done()
+
+	db := NewDB("test.db", bdb)
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("foo"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	const n = 8
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+
+			err := db.Batch(func(tx *bolt.Tx) error {
+				return tx.Bucket([]byte("foo")).Put([]byte(fmt.Sprintf("key%d", i)), nil)
+			})
+			if err != nil {
+				t.Errorf("failed to batch update database: %v", err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	var txs int
+	err = db.View(func(tx *bolt.Tx) error {
+		txs = tx.ID()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to view database: %v", err)
+	}
+
+	got := testCollector(t, db)
+
+	// Each committed transaction is observed exactly once, no matter how many
+	// Batch calls shared it.  Every write transaction was performed using db,
+	// so the number of observations matches the number of transactions
+	// committed since the database was created.
+	want := fmt.Sprintf(`bolt_write_tx_pages_allocated_count{database="test.db",operation="unknown"} %d`, txs-1)
+	if !strings.Contains(got, want) {
+		t.Fatalf("output did not contain expected metric: %q", want)
+	}
+}
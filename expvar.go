@@ -0,0 +1,60 @@
+package prombolt
+
+import (
+	"context"
+	"expvar"
+
+	"github.com/boltdb/bolt"
+)
+
+// PublishExpvar publishes an expvar.Func with the specified name, which
+// reports the statistics of a Bolt database handle and each of its buckets
+// as JSON when read, such as from the /debug/vars endpoint registered by
+// package expvar.
+//
+// As with expvar.Publish, PublishExpvar panics if a variable with the
+// specified name is already published.
+func PublishExpvar(name string, db *bolt.DB) {
+	expvar.Publish(name, expvar.Func(expvarStatsFunc(db, forEachWithBoltDB(name, db, 1))))
+}
+
+// expvarStats is the JSON representation of the statistics published by
+// PublishExpvar.
+type expvarStats struct {
+	Stats   bolt.Stats              `json:"stats"`
+	Buckets map[string]expvarBucket `json:"buckets"`
+
+	// Error is set if the bucket statistics could not be retrieved.
+	Error string `json:"error,omitempty"`
+}
+
+// expvarBucket is the JSON representation of the statistics of a bucket.
+type expvarBucket struct {
+	Stats    bolt.BucketStats `json:"stats"`
+	Sequence uint64           `json:"sequence"`
+}
+
+// expvarStatsFunc returns a function which retrieves statistics from ss and
+// each bucket iterated by forEach.
+func expvarStatsFunc(ss statser, forEach func(context.Context, forEachBucketStatsFunc) error) func() interface{} {
+	return func() interface{} {
+		s := expvarStats{
+			Stats:   ss.Stats(),
+			Buckets: make(map[string]expvarBucket),
+		}
+
+		err := forEach(context.Background(), func(b bucketInfo) error {
+			s.Buckets[b.Name] = expvarBucket{
+				Stats:    b.Stats,
+				Sequence: b.Sequence,
+			}
+
+			return nil
+		})
+		if err != nil {
+			s.Error = err.Error()
+		}
+
+		return s
+	}
+}
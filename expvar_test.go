@@ -0,0 +1,113 @@
+package prombolt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"reflect"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestExpvarStatsFunc(t *testing.T) {
+	errFoo := errors.New("foo")
+
+	tests := []struct {
+		name    string
+		buckets []memoryBucketStats
+		err     error
+		want    expvarStats
+	}{
+		{
+			name: "no buckets",
+			want: expvarStats{
+				Stats:   bolt.Stats{FreePageN: 1},
+				Buckets: map[string]expvarBucket{},
+			},
+		},
+		{
+			name: "buckets",
+			buckets: []memoryBucketStats{
+				{
+					name: "foo",
+					s:    bolt.BucketStats{KeyN: 1},
+					seq:  2,
+				},
+				{
+					name: "bar",
+					s:    bolt.BucketStats{KeyN: 3},
+				},
+			},
+			want: expvarStats{
+				Stats: bolt.Stats{FreePageN: 1},
+				Buckets: map[string]expvarBucket{
+					"foo": {
+						Stats:    bolt.BucketStats{KeyN: 1},
+						Sequence: 2,
+					},
+					"bar": {
+						Stats: bolt.BucketStats{KeyN: 3},
+					},
+				},
+			},
+		},
+		{
+			name: "error",
+			err:  errFoo,
+			want: expvarStats{
+				Stats:   bolt.Stats{FreePageN: 1},
+				Buckets: map[string]expvarBucket{},
+				Error:   "foo",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs := newMemoryBucketStatsCollector(tt.buckets)
+			forEach := func(ctx context.Context, fn forEachBucketStatsFunc) error {
+				if err := bs.forEach(ctx, fn); err != nil {
+					return err
+				}
+
+				return tt.err
+			}
+
+			fn := expvarStatsFunc(&memoryStatsCollector{s: tt.want.Stats}, forEach)
+
+			if want, got := tt.want, fn().(expvarStats); !reflect.DeepEqual(want, got) {
+				t.Fatalf("unexpected stats:\n- want: %+v\n-  got: %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestPublishExpvar(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("foo"))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte("foo"), []byte("bar"))
+	})
+	if err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+
+	PublishExpvar("prombolt_test", db)
+
+	var s expvarStats
+	if err := json.Unmarshal([]byte(expvar.Get("prombolt_test").String()), &s); err != nil {
+		t.Fatalf("failed to unmarshal JSON: %v", err)
+	}
+
+	if want, got := 1, s.Buckets["foo"].Stats.KeyN; want != got {
+		t.Fatalf("unexpected number of keys:\n- want: %v\n-  got: %v", want, got)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !darwin && !freebsd && !linux
+// +build !darwin,!freebsd,!linux
+
+package prombolt
+
+import (
+	"errors"
+)
+
+// filesystemSupported indicates if statfs is supported on this platform.
+const filesystemSupported = false
+
+// errFilesystemNotSupported is returned by statfs on platforms which do not
+// support it.
+var errFilesystemNotSupported = errors.New("prombolt: filesystem statistics not supported on this platform")
+
+// statfs is not supported on this platform.
+func statfs(_ string) (uint64, uint64, error) {
+	return 0, 0, errFilesystemNotSupported
+}
@@ -0,0 +1,30 @@
+//go:build darwin || freebsd || linux
+// +build darwin freebsd linux
+
+package prombolt
+
+import (
+	"syscall"
+)
+
+// filesystemSupported indicates if statfs is supported on this platform.
+const filesystemSupported = true
+
+// statfs uses statfs(2) to retrieve the number of bytes available to
+// unprivileged users and the total size of the filesystem containing path.
+func statfs(path string) (uint64, uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+
+	// Some platforms report a negative number of available blocks when the
+	// blocks reserved for privileged users are in use.
+	avail := int64(st.Bavail)
+	if avail < 0 {
+		avail = 0
+	}
+
+	bsize := uint64(st.Bsize)
+	return uint64(avail) * bsize, uint64(st.Blocks) * bsize, nil
+}
@@ -0,0 +1,105 @@
+package prombolt
+
+import (
+	"path/filepath"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ prometheus.Collector = &filesystemCollector{}
+
+// A filesystemCollector is a prometheus.Collector for the filesystem which
+// contains a Bolt database file.
+type filesystemCollector struct {
+	name  string
+	stats func() (filesystemStats, error)
+
+	filter metricFilter
+	descs  map[*metricDef]*prometheus.Desc
+}
+
+// filesystemStats contains statistics about the filesystem which contains a
+// Bolt database file.
+type filesystemStats struct {
+	// FreeBytes is the number of bytes available to unprivileged users.
+	FreeBytes uint64
+	// SizeBytes is the total size of the filesystem in bytes.
+	SizeBytes uint64
+	// AllocSize is the number of bytes by which Bolt grows the database file.
+	AllocSize int
+}
+
+// newFilesystemCollector creates a new filesystemCollector with the specified
+// name, function for retrieving filesystem statistics, filter for enabled
+// metrics, and metric naming.
+func newFilesystemCollector(name string, stats func() (filesystemStats, error), f metricFilter, n Naming) *filesystemCollector {
+	return &filesystemCollector{
+		name:   name,
+		stats:  stats,
+		filter: f,
+		descs:  newPromDescs(n, f.filter(filesystemMetricDefs)...),
+	}
+}
+
+// filesystemStatsWithBoltDB returns a function which retrieves statistics for
+// the filesystem containing the directory of a Bolt database file.
+func filesystemStatsWithBoltDB(db *bolt.DB) func() (filesystemStats, error) {
+	return func() (filesystemStats, error) {
+		free, size, err := statfs(filepath.Dir(db.Path()))
+		if err != nil {
+			return filesystemStats{}, err
+		}
+
+		return filesystemStats{
+			FreeBytes: free,
+			SizeBytes: size,
+			AllocSize: db.AllocSize,
+		}, nil
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *filesystemCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *filesystemCollector) Collect(ch chan<- prometheus.Metric) {
+	c.gather(&promSink{
+		ch:    ch,
+		name:  c.name,
+		descs: c.descs,
+	})
+}
+
+// gather sends the values of the metrics defined by filesystemMetricDefs
+// which are enabled to sink.
+func (c *filesystemCollector) gather(sink metricSink) {
+	defs := c.filter.filter(filesystemMetricDefs)
+	if len(defs) == 0 {
+		return
+	}
+
+	s, err := c.stats()
+	if err != nil {
+		sink.Fail(defs[0], err)
+		return
+	}
+
+	if c.filter.enabled(filesystemFreeBytesDef) {
+		sink.Observe(filesystemFreeBytesDef, "", float64(s.FreeBytes))
+	}
+
+	if c.filter.enabled(filesystemSizeBytesDef) {
+		sink.Observe(filesystemSizeBytesDef, "", float64(s.SizeBytes))
+	}
+
+	// Once the database is larger than its allocation size, Bolt grows the
+	// file in allocation size increments.
+	if s.AllocSize > 0 && c.filter.enabled(filesystemGrowthsRemainingDef) {
+		sink.Observe(filesystemGrowthsRemainingDef, "", float64(s.FreeBytes/uint64(s.AllocSize)))
+	}
+}
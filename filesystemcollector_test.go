@@ -0,0 +1,86 @@
+package prombolt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilesystemCollector(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        filesystemStats
+		matches  []string
+		excludes []string
+	}{
+		{
+			name: "OK",
+			s: filesystemStats{
+				FreeBytes: 100 * 1024,
+				SizeBytes: 1000 * 1024,
+				AllocSize: 16 * 1024,
+			},
+			matches: []string{
+				`bolt_db_filesystem_free_bytes{database="test.db"} 102400`,
+				`bolt_db_filesystem_size_bytes{database="test.db"} 1.024e+06`,
+				`bolt_db_filesystem_growths_remaining{database="test.db"} 6`,
+			},
+		},
+		{
+			name: "no allocation size",
+			s: filesystemStats{
+				FreeBytes: 1024,
+				SizeBytes: 2048,
+			},
+			matches: []string{
+				`bolt_db_filesystem_free_bytes{database="test.db"} 1024`,
+				`bolt_db_filesystem_size_bytes{database="test.db"} 2048`,
+			},
+			excludes: []string{
+				`bolt_db_filesystem_growths_remaining{`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newFilesystemCollector("test.db", func() (filesystemStats, error) {
+				return tt.s, nil
+			}, metricFilter{}, Naming{})
+
+			got := testCollector(t, fc)
+
+			for _, m := range tt.matches {
+				if !strings.Contains(got, m) {
+					t.Fatalf("output did not contain expected metric: %q", m)
+				}
+			}
+
+			for _, m := range tt.excludes {
+				if strings.Contains(got, m) {
+					t.Fatalf("output contained unexpected metric: %q", m)
+				}
+			}
+		})
+	}
+}
+
+func TestFilesystemStatsWithBoltDB(t *testing.T) {
+	if !filesystemSupported {
+		t.Skip("filesystem statistics not supported on this platform")
+	}
+
+	db, done := testDB(t)
+	defer done()
+
+	s, err := filesystemStatsWithBoltDB(db)()
+	if err != nil {
+		t.Fatalf("failed to retrieve filesystem statistics: %v", err)
+	}
+
+	if s.SizeBytes == 0 || s.FreeBytes > s.SizeBytes {
+		t.Fatalf("unexpected filesystem statistics: %+v", s)
+	}
+	if want, got := db.AllocSize, s.AllocSize; want != got {
+		t.Fatalf("unexpected allocation size:\n- want: %v\n-  got: %v", want, got)
+	}
+}
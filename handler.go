@@ -0,0 +1,101 @@
+package prombolt
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// scrapeTimeoutHeader is the HTTP header used by Prometheus to indicate
+	// the scrape timeout for a target, in seconds.
+	scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+	// scrapeTimeoutOffset is subtracted from a Prometheus scrape timeout, so
+	// that metrics can be written to the client before Prometheus gives up on
+	// the scrape.
+	scrapeTimeoutOffset = 500 * time.Millisecond
+)
+
+// Handler creates a new http.Handler which serves metrics from a Bolt
+// database handle in the Prometheus text format.  Name and options are
+// used in the same way as with New.
+//
+// If a request carries the X-Prometheus-Scrape-Timeout-Seconds header set by
+// Prometheus, it is used to derive a deadline for each metrics collection.
+// Metrics collection is also stopped if the client goes away.
+func Handler(name string, db *bolt.DB, options ...Option) http.Handler {
+	return &handler{
+		c: newCollector(name, db, options...),
+	}
+}
+
+// A handler is an http.Handler which serves metrics from a collector.
+type handler struct {
+	c *collector
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if d, ok := scrapeTimeout(r); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	// A registry is created for each request so that the request's context
+	// can be passed through to the collector.
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(&contextCollector{ctx: ctx, c: h.c}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// scrapeTimeout derives a metrics collection deadline from the Prometheus
+// scrape timeout header in r, if one is present.
+func scrapeTimeout(r *http.Request) (time.Duration, bool) {
+	v := r.Header.Get(scrapeTimeoutHeader)
+	if v == "" {
+		return 0, false
+	}
+
+	secs, err := strconv.ParseFloat(v, 64)
+	if err != nil || secs <= 0 {
+		return 0, false
+	}
+
+	d := time.Duration(secs * float64(time.Second))
+	if d > scrapeTimeoutOffset {
+		d -= scrapeTimeoutOffset
+	}
+
+	return d, true
+}
+
+var _ prometheus.Collector = &contextCollector{}
+
+// A contextCollector is a prometheus.Collector which passes a context to a
+// collector for a single metrics collection.
+type contextCollector struct {
+	ctx context.Context
+	c   *collector
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *contextCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.c.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *contextCollector) Collect(ch chan<- prometheus.Metric) {
+	c.c.collect(c.ctx, ch)
+}
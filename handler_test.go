@@ -0,0 +1,139 @@
+package prombolt
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestHandler(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("foo"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to populate database: %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		matches  []string
+		excludes []string
+	}{
+		{
+			name: "OK",
+			ctx:  context.Background(),
+			matches: []string{
+				`bolt_bucket_keys{bucket="foo",database="test.db"} 0`,
+				`bolt_db_open_read_tx{database="test.db"} 0`,
+				`bolt_scrape_truncated{database="test.db"} 0`,
+			},
+		},
+		{
+			name: "truncated",
+			ctx:  canceled,
+			matches: []string{
+				`bolt_db_open_read_tx{database="test.db"} 0`,
+				`bolt_scrape_truncated{database="test.db"} 1`,
+			},
+			excludes: []string{
+				`bolt_bucket_keys{bucket="foo",database="test.db"}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil).WithContext(tt.ctx)
+			w := httptest.NewRecorder()
+
+			Handler("test.db", db).ServeHTTP(w, r)
+
+			buf, err := ioutil.ReadAll(w.Body)
+			if err != nil {
+				t.Fatalf("failed to read response body: %v", err)
+			}
+			got := string(buf)
+
+			for _, m := range tt.matches {
+				if !strings.Contains(got, m) {
+					t.Fatalf("output did not contain expected metric: %q", m)
+				}
+			}
+
+			for _, m := range tt.excludes {
+				if strings.Contains(got, m) {
+					t.Fatalf("output contained unexpected metric: %q", m)
+				}
+			}
+		})
+	}
+}
+
+func TestScrapeTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		d      time.Duration
+		ok     bool
+	}{
+		{
+			name: "no header",
+		},
+		{
+			name:   "invalid",
+			header: "foo",
+		},
+		{
+			name:   "zero",
+			header: "0",
+		},
+		{
+			name:   "negative",
+			header: "-1",
+		},
+		{
+			name:   "short",
+			header: "0.25",
+			d:      250 * time.Millisecond,
+			ok:     true,
+		},
+		{
+			name:   "OK",
+			header: "10",
+			d:      9500 * time.Millisecond,
+			ok:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.header != "" {
+				r.Header.Set(scrapeTimeoutHeader, tt.header)
+			}
+
+			d, ok := scrapeTimeout(r)
+
+			if want, got := tt.ok, ok; want != got {
+				t.Fatalf("unexpected ok:\n- want: %v\n-  got: %v", want, got)
+			}
+			if want, got := tt.d, d; want != got {
+				t.Fatalf("unexpected timeout:\n- want: %v\n-  got: %v", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package prombolt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ Sink = &influxSink{}
+
+// An influxSink is a Sink which writes samples in the InfluxDB line protocol.
+type influxSink struct {
+	w io.Writer
+}
+
+// NewInfluxSink creates a Sink which writes samples to w in the InfluxDB line
+// protocol.  Each sample is written as a line with the sample's name as the
+// measurement, "database" and "bucket" tags, tags for the sample's Labels,
+// and a single "value" field.
+func NewInfluxSink(w io.Writer) Sink {
+	return &influxSink{w: w}
+}
+
+// WriteSamples implements Sink.
+func (s *influxSink) WriteSamples(_ context.Context, t time.Time, samples []Sample) error {
+	var buf bytes.Buffer
+	writeInfluxLines(&buf, t, samples)
+
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+var _ Sink = &influxHTTPSink{}
+
+// An influxHTTPSink is a Sink which writes samples in the InfluxDB line
+// protocol to an HTTP endpoint.
+type influxHTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewInfluxHTTPSink creates a Sink which writes samples in the InfluxDB line
+// protocol, as with NewInfluxSink, using an HTTP POST request to url.  url
+// should be the complete URL of the InfluxDB write endpoint, including any
+// database, organization, bucket, and precision query parameters; samples use
+// nanosecond precision.
+//
+// If client is nil, http.DefaultClient is used.
+func NewInfluxHTTPSink(url string, client *http.Client) Sink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &influxHTTPSink{
+		url:    url,
+		client: client,
+	}
+}
+
+// WriteSamples implements Sink.
+func (s *influxHTTPSink) WriteSamples(ctx context.Context, t time.Time, samples []Sample) error {
+	var buf bytes.Buffer
+	writeInfluxLines(&buf, t, samples)
+
+	req, err := http.NewRequest(http.MethodPost, s.url, &buf)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("unexpected HTTP status %q while writing to %s: %s",
+			res.Status, s.url, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
+
+var (
+	// influxMeasurementEscaper escapes InfluxDB line protocol measurements.
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+
+	// influxTagEscaper escapes InfluxDB line protocol tag values.
+	influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// writeInfluxLines writes samples gathered at time t to buf in the InfluxDB
+// line protocol.
+func writeInfluxLines(buf *bytes.Buffer, t time.Time, samples []Sample) {
+	ts := strconv.FormatInt(t.UnixNano(), 10)
+
+	for _, s := range samples {
+		buf.WriteString(influxMeasurementEscaper.Replace(s.Name))
+		buf.WriteString(",database=")
+		buf.WriteString(influxTagEscaper.Replace(s.Database))
+
+		// Tags with empty values are not permitted.
+		if s.Bucket != "" {
+			buf.WriteString(",bucket=")
+			buf.WriteString(influxTagEscaper.Replace(s.Bucket))
+		}
+
+		keys := make([]string, 0, len(s.Labels))
+		for k := range s.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if s.Labels[k] == "" {
+				continue
+			}
+
+			buf.WriteByte(',')
+			buf.WriteString(influxTagEscaper.Replace(k))
+			buf.WriteByte('=')
+			buf.WriteString(influxTagEscaper.Replace(s.Labels[k]))
+		}
+
+		buf.WriteString(" value=")
+		buf.WriteString(strconv.FormatFloat(s.Value, 'g', -1, 64))
+		buf.WriteByte(' ')
+		buf.WriteString(ts)
+		buf.WriteByte('\n')
+	}
+}
@@ -0,0 +1,105 @@
+package prombolt
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testSamples = []Sample{
+	{Name: "bolt_db_freelist_free_pages", Database: "test.db", Value: 1},
+	{Name: "bolt_tx_write_seconds_total", Counter: true, Database: "test.db", Value: 0.5},
+	{Name: "bolt_bucket_keys", Database: "test.db", Bucket: "foo bar,baz=qux", Value: 2},
+	{
+		Name:     "bolt_db_info",
+		Database: "test.db",
+		Labels: map[string]string{
+			"read_only": "false",
+			"page_size": "4096",
+		},
+		Value: 1,
+	},
+}
+
+func TestInfluxSink(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewInfluxSink(&buf)
+
+	if err := s.WriteSamples(context.Background(), time.Unix(1, 0), testSamples); err != nil {
+		t.Fatalf("failed to write samples: %v", err)
+	}
+
+	want := `bolt_db_freelist_free_pages,database=test.db value=1 1000000000
+bolt_tx_write_seconds_total,database=test.db value=0.5 1000000000
+bolt_bucket_keys,database=test.db,bucket=foo\ bar\,baz\=qux value=2 1000000000
+bolt_db_info,database=test.db,page_size=4096,read_only=false value=1 1000000000
+`
+
+	if got := buf.String(); want != got {
+		t.Fatalf("unexpected output:\n- want:\n%s\n-  got:\n%s", want, got)
+	}
+}
+
+func TestInfluxHTTPSink(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		ok     bool
+	}{
+		{
+			name:   "OK",
+			status: http.StatusNoContent,
+			ok:     true,
+		},
+		{
+			name:   "error",
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if want, got := http.MethodPost, r.Method; want != got {
+					t.Errorf("unexpected method:\n- want: %v\n-  got: %v", want, got)
+				}
+				if want, got := "/write", r.URL.Path; want != got {
+					t.Errorf("unexpected path:\n- want: %v\n-  got: %v", want, got)
+				}
+
+				b, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("failed to read body: %v", err)
+				}
+				body = b
+
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			s := NewInfluxHTTPSink(srv.URL+"/write?db=bolt", nil)
+
+			err := s.WriteSamples(context.Background(), time.Unix(1, 0), testSamples[:1])
+			if tt.ok && err != nil {
+				t.Fatalf("failed to write samples: %v", err)
+			}
+			if !tt.ok {
+				if err == nil {
+					t.Fatal("expected an error, but none occurred")
+				}
+
+				return
+			}
+
+			want := "bolt_db_freelist_free_pages,database=test.db value=1 1000000000\n"
+			if got := string(body); want != got {
+				t.Fatalf("unexpected body:\n- want: %q\n-  got: %q", want, got)
+			}
+		})
+	}
+}
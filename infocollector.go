@@ -0,0 +1,76 @@
+package prombolt
+
+import (
+	"strconv"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ prometheus.Collector = &infoCollector{}
+
+// An infoCollector is a prometheus.Collector for information about how a Bolt
+// database was opened and configured.
+type infoCollector struct {
+	name     string
+	db       *bolt.DB
+	pageSize func() (int, error)
+
+	filter metricFilter
+	descs  map[*metricDef]*prometheus.Desc
+}
+
+// newInfoCollector creates a new infoCollector with the specified name, Bolt
+// database handle, function for retrieving the database page size, filter
+// for enabled metrics, and metric naming.
+func newInfoCollector(name string, db *bolt.DB, pageSize func() (int, error), f metricFilter, n Naming) *infoCollector {
+	return &infoCollector{
+		name:     name,
+		db:       db,
+		pageSize: pageSize,
+		filter:   f,
+		descs:    newPromDescs(n, f.filter(infoMetricDefs)...),
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *infoCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *infoCollector) Collect(ch chan<- prometheus.Metric) {
+	c.gather(&promSink{
+		ch:    ch,
+		name:  c.name,
+		descs: c.descs,
+	})
+}
+
+// gather sends the value of the metric defined by infoDef to sink, if it is
+// enabled.
+func (c *infoCollector) gather(sink metricSink) {
+	if !c.filter.enabled(infoDef) {
+		return
+	}
+
+	pageSize, err := c.pageSize()
+	if err != nil {
+		sink.Fail(infoDef, err)
+		return
+	}
+
+	sink.Observe(infoDef, "", 1,
+		strconv.FormatBool(c.db.NoSync),
+		strconv.FormatBool(c.db.NoGrowSync),
+		strconv.FormatBool(c.db.IsReadOnly()),
+		strconv.FormatBool(c.db.StrictMode),
+		strconv.Itoa(c.db.MmapFlags),
+		strconv.Itoa(c.db.AllocSize),
+		strconv.Itoa(c.db.MaxBatchSize),
+		strconv.FormatFloat(c.db.MaxBatchDelay.Seconds(), 'f', -1, 64),
+		strconv.Itoa(pageSize),
+	)
+}
@@ -0,0 +1,56 @@
+package prombolt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestInfoCollector(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	db.NoSync = true
+	db.StrictMode = true
+	db.AllocSize = 1024
+	db.MaxBatchSize = 10
+	db.MaxBatchDelay = 5 * time.Millisecond
+
+	got := testCollector(t, newInfoCollector("test.db", db, testPageSize(db), metricFilter{}, Naming{}))
+
+	want := fmt.Sprintf(
+		`bolt_db_info{alloc_size="1024",database="test.db",max_batch_delay_seconds="0.005",max_batch_size="10",mmap_flags="0",no_grow_sync="false",no_sync="true",page_size="%d",read_only="false",strict_mode="true"} 1`,
+		os.Getpagesize(),
+	)
+
+	if !strings.Contains(got, want) {
+		t.Fatalf("output did not contain expected metric: %q", want)
+	}
+}
+
+func TestInfoCollectorClosed(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+
+	got := testCollector(t, newInfoCollector("test.db", db, testPageSize(db), metricFilter{}, Naming{}))
+
+	if strings.Contains(got, "bolt_db_info{") {
+		t.Fatal("output contained metric for closed database")
+	}
+}
+
+// testPageSize returns a function which retrieves the page size of db.
+func testPageSize(db *bolt.DB) func() (int, error) {
+	return func() (int, error) {
+		s, err := snapshotWithBoltDB(db)()
+		return s.PageSize, err
+	}
+}
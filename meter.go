@@ -0,0 +1,135 @@
+package prombolt
+
+import (
+	"context"
+	"errors"
+
+	"github.com/boltdb/bolt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// RegisterMeter registers OpenTelemetry observable instruments, created by a
+// Meter from mp, which report the same statistics as the metrics exported by
+// the prometheus.Collector returned by New.  No Prometheus registry is needed.
+// The transaction metrics recorded by a DB are not reported.
+//
+// Instrument names are derived from the Prometheus metric names, with the
+// namespace and subsystem separated by dots, and units and "_total" suffixes
+// replaced by the instrument's unit and kind.  For example,
+// bolt_tx_write_seconds_total is reported as the counter bolt.tx.write_time,
+// with unit "s".  Name is added as the "database" attribute of all
+// measurements, and Prometheus labels, such as those of bolt_db_info, are
+// added as attributes.
+//
+// Options configure the statistics reported as they do for New, and the
+// DisableGroups and DisableMetrics Options determine which instruments are
+// created.  MetricNaming has no effect.  Call Unregister on the returned
+// metric.Registration to stop reporting statistics.
+func RegisterMeter(name string, db *bolt.DB, mp metric.MeterProvider, options ...Option) (metric.Registration, error) {
+	m, err := newMeterCollector(mp.Meter(instrumentationName), newCollector(name, db, options...))
+	if err != nil {
+		return nil, err
+	}
+
+	return m.register()
+}
+
+// A meterCollector reports Bolt statistics using OpenTelemetry observable
+// instruments.
+type meterCollector struct {
+	meter metric.Meter
+	c     *collector
+	insts map[*metricDef]metric.Float64Observable
+}
+
+// newMeterCollector creates a new meterCollector which creates instruments
+// using meter, and reports statistics gathered by c.  Instruments are only
+// created for the metrics enabled for c.
+func newMeterCollector(meter metric.Meter, c *collector) (*meterCollector, error) {
+	insts := make(map[*metricDef]metric.Float64Observable, len(c.descs))
+	for d := range c.descs {
+		inst, err := newInstrument(meter, d)
+		if err != nil {
+			return nil, err
+		}
+
+		insts[d] = inst
+	}
+
+	return &meterCollector{
+		meter: meter,
+		c:     c,
+		insts: insts,
+	}, nil
+}
+
+// newInstrument creates an observable counter or gauge for the metric defined
+// by d.
+func newInstrument(meter metric.Meter, d *metricDef) (metric.Float64Observable, error) {
+	if d.Counter {
+		return meter.Float64ObservableCounter(d.OTelName,
+			metric.WithUnit(d.OTelUnit),
+			metric.WithDescription(d.Help),
+		)
+	}
+
+	return meter.Float64ObservableGauge(d.OTelName,
+		metric.WithUnit(d.OTelUnit),
+		metric.WithDescription(d.Help),
+	)
+}
+
+// register registers m's callback for all of its instruments.
+func (m *meterCollector) register() (metric.Registration, error) {
+	insts := make([]metric.Observable, 0, len(m.insts))
+	for _, inst := range m.insts {
+		insts = append(insts, inst)
+	}
+
+	return m.meter.RegisterCallback(m.observe, insts...)
+}
+
+// observe implements metric.Callback.  Callbacks may be invoked concurrently
+// by multiple readers, but the collector serializes them, so that bucket
+// changes are tracked correctly.
+func (m *meterCollector) observe(ctx context.Context, o metric.Observer) error {
+	sink := &meterSink{
+		o:     o,
+		name:  m.c.name,
+		insts: m.insts,
+	}
+
+	m.c.gather(ctx, sink)
+
+	return errors.Join(sink.errs...)
+}
+
+var _ metricSink = &meterSink{}
+
+// A meterSink is a metricSink which records observations of OpenTelemetry
+// instruments.
+type meterSink struct {
+	o     metric.Observer
+	name  string
+	insts map[*metricDef]metric.Float64Observable
+	errs  []error
+}
+
+// Observe implements metricSink.
+func (s *meterSink) Observe(d *metricDef, bucket string, v float64, labels ...string) {
+	attrs := []attribute.KeyValue{attribute.String("database", s.name)}
+	if d.Bucket {
+		attrs = append(attrs, attribute.String("bucket", bucket))
+	}
+	for i, l := range d.Labels {
+		attrs = append(attrs, attribute.String(l, labels[i]))
+	}
+
+	s.o.ObserveFloat64(s.insts[d], v, metric.WithAttributes(attrs...))
+}
+
+// Fail implements metricSink.
+func (s *meterSink) Fail(_ *metricDef, err error) {
+	s.errs = append(s.errs, err)
+}
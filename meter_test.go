@@ -0,0 +1,292 @@
+package prombolt
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMeterCollector(t *testing.T) {
+	bdb, done := testDB(t)
+	defer done()
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	bs := newMemoryBucketStatsCollector([]memoryBucketStats{{
+		name: "foo",
+		s: bolt.BucketStats{
+			KeyN:  2,
+			Depth: 1,
+		},
+		seq:  3,
+		root: 4,
+	}})
+
+	bs.now = func() time.Time {
+		return time.Unix(1, 0)
+	}
+
+	m, err := newMeterCollector(
+		mp.Meter(instrumentationName),
+		newMemoryCollector(bdb, bolt.Stats{
+			FreePageN: 1,
+			TxStats: bolt.TxStats{
+				WriteTime: 2 * time.Second,
+			},
+		}, 5, bs),
+	)
+	if err != nil {
+		t.Fatalf("failed to create meter collector: %v", err)
+	}
+
+	reg, err := m.register()
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+
+	rm := testMeterCollect(t, reader)
+
+	db := attribute.NewSet(attribute.String("database", "test.db"))
+	bucket := attribute.NewSet(
+		attribute.String("database", "test.db"),
+		attribute.String("bucket", "foo"),
+	)
+
+	tests := []struct {
+		name    string
+		unit    string
+		attrs   attribute.Set
+		counter bool
+		value   float64
+	}{
+		{
+			name:  "bolt.db.freelist_free_pages",
+			unit:  "{page}",
+			attrs: db,
+			value: 1,
+		},
+		{
+			name:    "bolt.db.last_txid",
+			attrs:   db,
+			counter: true,
+			value:   5,
+		},
+		{
+			name:  "bolt.db.mmap_size",
+			unit:  "By",
+			attrs: db,
+			value: 32768,
+		},
+		{
+			name:  "bolt.db.filesystem_free",
+			unit:  "By",
+			attrs: db,
+			value: 8192,
+		},
+		{
+			name:    "bolt.tx.write_time",
+			unit:    "s",
+			attrs:   db,
+			counter: true,
+			value:   2,
+		},
+		{
+			name:  "bolt.bucket.keys",
+			unit:  "{key}",
+			attrs: bucket,
+			value: 2,
+		},
+		{
+			name:  "bolt.bucket.sequence",
+			attrs: bucket,
+			value: 3,
+		},
+		{
+			name:  "bolt.bucket.last_change_timestamp",
+			unit:  "s",
+			attrs: bucket,
+			value: 1,
+		},
+		{
+			name:    "bolt.bucket.changes",
+			unit:    "{change}",
+			attrs:   bucket,
+			counter: true,
+			value:   0,
+		},
+		{
+			name:  "bolt.scrape_truncated",
+			attrs: db,
+			value: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metric, ok := findMetric(rm, tt.name)
+			if !ok {
+				t.Fatalf("metric %q was not reported", tt.name)
+			}
+
+			if want, got := tt.unit, metric.Unit; want != got {
+				t.Fatalf("unexpected unit:\n- want: %q\n-  got: %q", want, got)
+			}
+
+			var points []metricdata.DataPoint[float64]
+			switch data := metric.Data.(type) {
+			case metricdata.Sum[float64]:
+				if !tt.counter {
+					t.Fatal("expected a gauge, but got a counter")
+				}
+				points = data.DataPoints
+			case metricdata.Gauge[float64]:
+				if tt.counter {
+					t.Fatal("expected a counter, but got a gauge")
+				}
+				points = data.DataPoints
+			default:
+				t.Fatalf("unexpected metric data type: %T", data)
+			}
+
+			if want, got := 1, len(points); want != got {
+				t.Fatalf("unexpected number of data points:\n- want: %v\n-  got: %v", want, got)
+			}
+
+			if !tt.attrs.Equals(&points[0].Attributes) {
+				t.Fatalf("unexpected attributes:\n- want: %v\n-  got: %v",
+					tt.attrs.Encoded(attribute.DefaultEncoder()),
+					points[0].Attributes.Encoded(attribute.DefaultEncoder()))
+			}
+
+			if want, got := tt.value, points[0].Value; want != got {
+				t.Fatalf("unexpected value:\n- want: %v\n-  got: %v", want, got)
+			}
+		})
+	}
+
+	// bolt.db.info reports the options of the database as attributes.
+	info, ok := findMetric(rm, "bolt.db.info")
+	if !ok {
+		t.Fatal("metric \"bolt.db.info\" was not reported")
+	}
+
+	points := info.Data.(metricdata.Gauge[float64]).DataPoints
+	if want, got := 1, len(points); want != got {
+		t.Fatalf("unexpected number of data points:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	if v, _ := points[0].Attributes.Value("page_size"); v.AsString() != "4096" {
+		t.Fatalf("unexpected page_size attribute: %q", v.AsString())
+	}
+
+	if err := reg.Unregister(); err != nil {
+		t.Fatalf("failed to unregister callback: %v", err)
+	}
+
+	rm = testMeterCollect(t, reader)
+	if _, ok := findMetric(rm, "bolt.bucket.keys"); ok {
+		t.Fatal("metric was reported after unregistering callback")
+	}
+}
+
+func TestRegisterMeter(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("foo"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	reg, err := RegisterMeter("test.db", db, mp, Workers(2))
+	if err != nil {
+		t.Fatalf("failed to register meter: %v", err)
+	}
+	defer reg.Unregister()
+
+	rm := testMeterCollect(t, reader)
+
+	for _, name := range []string{
+		"bolt.db.read_tx",
+		"bolt.db.last_txid",
+		"bolt.db.info",
+		"bolt.db.mmap_remaps",
+		"bolt.bucket.depth",
+	} {
+		if _, ok := findMetric(rm, name); !ok {
+			t.Fatalf("metric %q was not reported", name)
+		}
+	}
+}
+
+func TestRegisterMeterMatchesCollector(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("foo"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	reg, err := RegisterMeter("test.db", db, mp)
+	if err != nil {
+		t.Fatalf("failed to register meter: %v", err)
+	}
+	defer reg.Unregister()
+
+	rm := testMeterCollect(t, reader)
+
+	// Every metric exported by the collector returned by New must also be
+	// reported by an instrument.  The memory map size is only known on Linux.
+	for d := range newCollector("test.db", db).descs {
+		if d == mmapSizeBytesDef && runtime.GOOS != "linux" {
+			continue
+		}
+
+		if _, ok := findMetric(rm, d.OTelName); !ok {
+			t.Fatalf("metric %q was not reported as %q", d.FQName(), d.OTelName)
+		}
+	}
+}
+
+// testMeterCollect performs a single collection using reader.
+func testMeterCollect(t *testing.T, reader sdkmetric.Reader) metricdata.ResourceMetrics {
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	return rm
+}
+
+// findMetric finds the metric with the specified name in rm.
+func findMetric(rm metricdata.ResourceMetrics, name string) (metricdata.Metrics, bool) {
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m, true
+			}
+		}
+	}
+
+	return metricdata.Metrics{}, false
+}
@@ -0,0 +1,806 @@
+package prombolt
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// A metricDef defines a metric derived from Bolt statistics, independently of
+// the system to which it is exported.
+type metricDef struct {
+	// Subsystem and Name form the fully-qualified Prometheus metric name,
+	// which is also used as the name of a Sample.
+	Subsystem string
+	Name      string
+	Help      string
+
+	// Counter reports whether the metric is a cumulative counter, rather than
+	// a gauge.
+	Counter bool
+
+	// Bucket reports whether the metric has a value for each bucket.
+	Bucket bool
+
+	// Labels are the names of the labels of the metric other than "database"
+	// and "bucket", whose values are passed to metricSink.Observe.
+	Labels []string
+
+	// OTelName and OTelUnit are the name and unit of the OpenTelemetry
+	// instrument for the metric.
+	OTelName string
+	OTelUnit string
+}
+
+// FQName returns the default fully-qualified Prometheus metric name for d.
+func (d *metricDef) FQName() string {
+	return prometheus.BuildFQName(namespace, d.Subsystem, d.Name)
+}
+
+// A statsDef defines a metric derived from bolt.Stats.
+type statsDef struct {
+	metricDef
+	Value func(s bolt.Stats) float64
+}
+
+// A bucketDef defines a metric derived from the statistics of a bucket, and
+// the changes tracked for it.
+type bucketDef struct {
+	metricDef
+	Value func(b bucketInfo, bc *bucketChange) float64
+}
+
+var (
+	// lastTxIDDef defines the metric for the ID of the last committed
+	// transaction.  The ID increases with each commit, so it is a counter
+	// whose rate is the commit rate.
+	lastTxIDDef = &metricDef{
+		Subsystem: "db",
+		Name:      "last_txid",
+		Help:      "ID of the last committed transaction for the database, which increases with each commit.",
+		Counter:   true,
+		OTelName:  "bolt.db.last_txid",
+	}
+
+	// scrapeTruncatedDef defines the metric which reports whether a bucket
+	// walk was stopped early.
+	scrapeTruncatedDef = &metricDef{
+		Name:     "scrape_truncated",
+		Help:     "Whether the bucket statistics walk was stopped early due to a scrape deadline.",
+		OTelName: "bolt.scrape_truncated",
+	}
+
+	// infoDef defines the metric which reports the options used to open and
+	// configure a database as labels.
+	infoDef = &metricDef{
+		Subsystem: "db",
+		Name:      "info",
+		Help:      "Metric with a constant value of 1, labeled with the options used to open and configure the database.",
+		Labels: []string{
+			"no_sync",
+			"no_grow_sync",
+			"read_only",
+			"strict_mode",
+			"mmap_flags",
+			"alloc_size",
+			"max_batch_size",
+			"max_batch_delay_seconds",
+			"page_size",
+		},
+		OTelName: "bolt.db.info",
+	}
+
+	// mmapRemapsTotalDef, fileGrowEventsTotalDef, and mmapSizeBytesDef define
+	// the metrics for the memory map and file growth of a database.
+	mmapRemapsTotalDef = &metricDef{
+		Subsystem: "db",
+		Name:      "mmap_remaps_total",
+		Help:      "Total number of collections in which the database memory map was observed to have been remapped.",
+		Counter:   true,
+		OTelName:  "bolt.db.mmap_remaps",
+		OTelUnit:  "{remap}",
+	}
+
+	fileGrowEventsTotalDef = &metricDef{
+		Subsystem: "db",
+		Name:      "file_grow_events_total",
+		Help:      "Total number of collections in which the database file was observed to have grown.",
+		Counter:   true,
+		OTelName:  "bolt.db.file_grow_events",
+		OTelUnit:  "{event}",
+	}
+
+	mmapSizeBytesDef = &metricDef{
+		Subsystem: "db",
+		Name:      "mmap_size_bytes",
+		Help:      "Size of the database memory map in bytes.",
+		OTelName:  "bolt.db.mmap_size",
+		OTelUnit:  "By",
+	}
+
+	// filesystemFreeBytesDef, filesystemSizeBytesDef, and
+	// filesystemGrowthsRemainingDef define the metrics for the filesystem
+	// containing a database file.
+	filesystemFreeBytesDef = &metricDef{
+		Subsystem: "db",
+		Name:      "filesystem_free_bytes",
+		Help:      "Number of bytes available to unprivileged users on the filesystem containing the database.",
+		OTelName:  "bolt.db.filesystem_free",
+		OTelUnit:  "By",
+	}
+
+	filesystemSizeBytesDef = &metricDef{
+		Subsystem: "db",
+		Name:      "filesystem_size_bytes",
+		Help:      "Size in bytes of the filesystem containing the database.",
+		OTelName:  "bolt.db.filesystem_size",
+		OTelUnit:  "By",
+	}
+
+	filesystemGrowthsRemainingDef = &metricDef{
+		Subsystem: "db",
+		Name:      "filesystem_growths_remaining",
+		Help:      "Estimated number of times the database file can grow by its allocation size before the filesystem is full.",
+		OTelName:  "bolt.db.filesystem_growths_remaining",
+		OTelUnit:  "{growth}",
+	}
+
+	// residentBytesDef and residentRatioDef define the metrics for the page
+	// cache residency of a database file.
+	residentBytesDef = &metricDef{
+		Subsystem: "db",
+		Name:      "resident_bytes",
+		Help:      "Number of bytes of the database file which are resident in the page cache.",
+		OTelName:  "bolt.db.resident",
+		OTelUnit:  "By",
+	}
+
+	residentRatioDef = &metricDef{
+		Subsystem: "db",
+		Name:      "resident_ratio",
+		Help:      "Ratio of the database file which is resident in the page cache.",
+		OTelName:  "bolt.db.resident_ratio",
+		OTelUnit:  "1",
+	}
+)
+
+// The metricDefs of the metrics reported by each collector which does not
+// use a table of statsDefs or bucketDefs.
+var (
+	infoMetricDefs = []*metricDef{infoDef}
+
+	mmapMetricDefs = []*metricDef{
+		mmapRemapsTotalDef,
+		fileGrowEventsTotalDef,
+		mmapSizeBytesDef,
+	}
+
+	filesystemMetricDefs = []*metricDef{
+		filesystemFreeBytesDef,
+		filesystemSizeBytesDef,
+		filesystemGrowthsRemainingDef,
+	}
+
+	residencyMetricDefs = []*metricDef{
+		residentBytesDef,
+		residentRatioDef,
+	}
+)
+
+// statsDefs are the definitions of the metrics derived from bolt.Stats.
+var statsDefs = []statsDef{
+	{
+		metricDef: metricDef{
+			Subsystem: "db",
+			Name:      "freelist_free_pages",
+			Help:      "Number of free pages on the freelist.",
+			OTelName:  "bolt.db.freelist_free_pages",
+			OTelUnit:  "{page}",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.FreePageN) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "db",
+			Name:      "freelist_pending_pages",
+			Help:      "Number of pending pages on the freelist.",
+			OTelName:  "bolt.db.freelist_pending_pages",
+			OTelUnit:  "{page}",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.PendingPageN) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "db",
+			Name:      "freelist_free_page_allocated_bytes",
+			Help:      "Number of bytes allocated in free pages on the freelist.",
+			OTelName:  "bolt.db.freelist_free_page_allocated",
+			OTelUnit:  "By",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.FreeAlloc) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "db",
+			Name:      "freelist_in_use_bytes",
+			Help:      "Number of bytes in use by the freelist.",
+			OTelName:  "bolt.db.freelist_in_use",
+			OTelUnit:  "By",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.FreelistInuse) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "db",
+			Name:      "read_tx_total",
+			Help:      "Total number of started read transactions for the database.",
+			Counter:   true,
+			OTelName:  "bolt.db.read_tx",
+			OTelUnit:  "{transaction}",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.TxN) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "db",
+			Name:      "open_read_tx",
+			Help:      "Number of currently open read-only transactions for the database.",
+			OTelName:  "bolt.db.open_read_tx",
+			OTelUnit:  "{transaction}",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.OpenTxN) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "tx",
+			Name:      "pages_allocated_total",
+			Help:      "Total number of transaction page allocations.",
+			Counter:   true,
+			OTelName:  "bolt.tx.pages_allocated",
+			OTelUnit:  "{page}",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.TxStats.PageCount) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "tx",
+			Name:      "pages_allocated_bytes_total",
+			Help:      "Total number of bytes allocated for transaction pages.",
+			Counter:   true,
+			OTelName:  "bolt.tx.pages_allocated_size",
+			OTelUnit:  "By",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.TxStats.PageAlloc) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "tx",
+			Name:      "cursors_total",
+			Help:      "Total number of cursors created by transactions",
+			Counter:   true,
+			OTelName:  "bolt.tx.cursors",
+			OTelUnit:  "{cursor}",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.TxStats.CursorCount) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "tx",
+			Name:      "nodes_allocated_total",
+			Help:      "Total number of nodes allocated by transactions.",
+			Counter:   true,
+			OTelName:  "bolt.tx.nodes_allocated",
+			OTelUnit:  "{node}",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.TxStats.NodeCount) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "tx",
+			Name:      "nodes_dereferenced_total",
+			Help:      "Total number of nodes dereferenced by transactions.",
+			Counter:   true,
+			OTelName:  "bolt.tx.nodes_dereferenced",
+			OTelUnit:  "{node}",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.TxStats.NodeDeref) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "tx",
+			Name:      "node_rebalances_total",
+			Help:      "Total number of node rebalances by transactions.",
+			Counter:   true,
+			OTelName:  "bolt.tx.node_rebalances",
+			OTelUnit:  "{rebalance}",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.TxStats.Rebalance) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "tx",
+			Name:      "node_rebalance_seconds_total",
+			Help:      "Total amount of time in seconds spent rebalancing nodes by transactions",
+			Counter:   true,
+			OTelName:  "bolt.tx.node_rebalance_time",
+			OTelUnit:  "s",
+		},
+		Value: func(s bolt.Stats) float64 { return s.TxStats.RebalanceTime.Seconds() },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "tx",
+			Name:      "nodes_split_total",
+			Help:      "Total number of nodes split by transactions.",
+			Counter:   true,
+			OTelName:  "bolt.tx.nodes_split",
+			OTelUnit:  "{node}",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.TxStats.Split) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "tx",
+			Name:      "nodes_spilled_total",
+			Help:      "Total number of nodes spilled by transactions.",
+			Counter:   true,
+			OTelName:  "bolt.tx.nodes_spilled",
+			OTelUnit:  "{node}",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.TxStats.Spill) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "tx",
+			Name:      "nodes_spilled_seconds_total",
+			Help:      "Total amount of time in seconds spent spilling nodes by transactions.",
+			Counter:   true,
+			OTelName:  "bolt.tx.nodes_spilled_time",
+			OTelUnit:  "s",
+		},
+		Value: func(s bolt.Stats) float64 { return s.TxStats.SpillTime.Seconds() },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "tx",
+			Name:      "writes_total",
+			Help:      "Total number of writes to disk performed by transactions.",
+			Counter:   true,
+			OTelName:  "bolt.tx.writes",
+			OTelUnit:  "{write}",
+		},
+		Value: func(s bolt.Stats) float64 { return float64(s.TxStats.Write) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "tx",
+			Name:      "write_seconds_total",
+			Help:      "Total amount of time in seconds spent writing to disk by transactions.",
+			Counter:   true,
+			OTelName:  "bolt.tx.write_time",
+			OTelUnit:  "s",
+		},
+		Value: func(s bolt.Stats) float64 { return s.TxStats.WriteTime.Seconds() },
+	},
+}
+
+// bucketDefs are the definitions of the metrics derived from the statistics
+// of each bucket.
+var bucketDefs = []bucketDef{
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "logical_branch_pages",
+			Help:      "Number of logical branch pages for a bucket.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.logical_branch_pages",
+			OTelUnit:  "{page}",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.BranchPageN) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "physical_branch_overflow_pages",
+			Help:      "Number of physical branch overflow pages for a bucket.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.physical_branch_overflow_pages",
+			OTelUnit:  "{page}",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.BranchOverflowN) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "logical_leaf_pages",
+			Help:      "Number of logical leaf pages for a bucket.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.logical_leaf_pages",
+			OTelUnit:  "{page}",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.LeafPageN) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "physical_leaf_overflow_pages",
+			Help:      "Number of physical leaf overflow pages for a bucket.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.physical_leaf_overflow_pages",
+			OTelUnit:  "{page}",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.LeafOverflowN) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "keys",
+			Help:      "Number of key/value pairs in a bucket.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.keys",
+			OTelUnit:  "{key}",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.KeyN) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "depth",
+			Help:      "Number of levels in B+ tree for a bucket.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.depth",
+			OTelUnit:  "{level}",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.Depth) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "physical_branch_pages_allocated_bytes",
+			Help:      "Number of bytes allocated in physical branch pages for a bucket.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.physical_branch_pages_allocated",
+			OTelUnit:  "By",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.BranchAlloc) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "physical_branch_pages_in_use_bytes",
+			Help:      "Number of bytes in use in physical branch pages for a bucket.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.physical_branch_pages_in_use",
+			OTelUnit:  "By",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.BranchInuse) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "physical_leaf_pages_allocated_bytes",
+			Help:      "Number of bytes allocated in physical leaf pages for a bucket.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.physical_leaf_pages_allocated",
+			OTelUnit:  "By",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.LeafAlloc) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "physical_leaf_pages_in_use_bytes",
+			Help:      "Number of bytes in use in physical leaf pages for a bucket.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.physical_leaf_pages_in_use",
+			OTelUnit:  "By",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.LeafInuse) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "buckets",
+			Help:      "Number of buckets within a bucket, including the top bucket.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.buckets",
+			OTelUnit:  "{bucket}",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.BucketN) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "inlined_buckets",
+			Help:      "Number of inlined buckets for a bucket.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.inlined_buckets",
+			OTelUnit:  "{bucket}",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.InlineBucketN) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "inlined_buckets_in_use_bytes",
+			Help:      "Number of bytes in use for inlined buckets.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.inlined_buckets_in_use",
+			OTelUnit:  "By",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Stats.InlineBucketInuse) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "sequence",
+			Help:      "Current sequence number for a bucket, as incremented by NextSequence.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.sequence",
+		},
+		Value: func(b bucketInfo, _ *bucketChange) float64 { return float64(b.Sequence) },
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "last_change_timestamp_seconds",
+			Help:      "UNIX timestamp of the collection in which a change to a bucket was last detected, or in which the bucket was first seen.",
+			Bucket:    true,
+			OTelName:  "bolt.bucket.last_change_timestamp",
+			OTelUnit:  "s",
+		},
+		Value: func(_ bucketInfo, bc *bucketChange) float64 {
+			return float64(bc.last.UnixNano()) / float64(time.Second)
+		},
+	},
+	{
+		metricDef: metricDef{
+			Subsystem: "bucket",
+			Name:      "changes_total",
+			Help:      "Total number of collections in which a change to a bucket was detected.",
+			Counter:   true,
+			Bucket:    true,
+			OTelName:  "bolt.bucket.changes",
+			OTelUnit:  "{change}",
+		},
+		Value: func(_ bucketInfo, bc *bucketChange) float64 { return float64(bc.total) },
+	},
+}
+
+// statsMetricDefs returns the metricDefs for lastTxIDDef and statsDefs.
+func statsMetricDefs() []*metricDef {
+	defs := []*metricDef{lastTxIDDef}
+	for i := range statsDefs {
+		defs = append(defs, &statsDefs[i].metricDef)
+	}
+
+	return defs
+}
+
+// bucketMetricDefs returns the metricDefs for scrapeTruncatedDef and
+// bucketDefs.
+func bucketMetricDefs() []*metricDef {
+	defs := []*metricDef{scrapeTruncatedDef}
+	for i := range bucketDefs {
+		defs = append(defs, &bucketDefs[i].metricDef)
+	}
+
+	return defs
+}
+
+// allMetricDefs returns the metricDefs of every metric reported by the
+// collector returned by New.
+func allMetricDefs() []*metricDef {
+	var defs []*metricDef
+	defs = append(defs, infoMetricDefs...)
+	defs = append(defs, statsMetricDefs()...)
+	defs = append(defs, mmapMetricDefs...)
+	defs = append(defs, filesystemMetricDefs...)
+	defs = append(defs, residencyMetricDefs...)
+	defs = append(defs, bucketMetricDefs()...)
+
+	return defs
+}
+
+// A metricFilter determines which metrics are enabled, using the groups and
+// metric names disabled by Options.  The zero value enables all metrics.
+type metricFilter struct {
+	groups  map[string]bool
+	metrics map[string]bool
+
+	// naming is used to match groups and metric names which use the names
+	// configured by MetricNaming, in addition to the default names.
+	naming Naming
+}
+
+// enabled reports whether the metric defined by d is enabled.  The group of a
+// metric is its subsystem.
+func (f metricFilter) enabled(d *metricDef) bool {
+	if f.groups[d.Subsystem] || f.groups[f.naming.subsystem(d.Subsystem)] {
+		return false
+	}
+
+	return !f.metrics[d.FQName()] && !f.metrics[f.naming.fqName(d.Subsystem, d.Name)]
+}
+
+// unknown returns the disabled groups and metric names which do not match
+// any metric.
+func (f metricFilter) unknown() (groups, metrics []string) {
+	knownGroups := make(map[string]bool)
+	knownMetrics := make(map[string]bool)
+	for _, d := range allMetricDefs() {
+		if d.Subsystem != "" {
+			knownGroups[d.Subsystem] = true
+			knownGroups[f.naming.subsystem(d.Subsystem)] = true
+		}
+
+		knownMetrics[d.FQName()] = true
+		knownMetrics[f.naming.fqName(d.Subsystem, d.Name)] = true
+	}
+
+	for g := range f.groups {
+		if !knownGroups[g] {
+			groups = append(groups, g)
+		}
+	}
+
+	for m := range f.metrics {
+		if !knownMetrics[m] {
+			metrics = append(metrics, m)
+		}
+	}
+
+	sort.Strings(groups)
+	sort.Strings(metrics)
+	return groups, metrics
+}
+
+// filter returns the metricDefs in defs which are enabled.
+func (f metricFilter) filter(defs []*metricDef) []*metricDef {
+	var out []*metricDef
+	for _, d := range defs {
+		if f.enabled(d) {
+			out = append(out, d)
+		}
+	}
+
+	return out
+}
+
+// bucketsEnabled reports whether any metric defined by bucketDefs is enabled.
+// If not, the bucket statistics walk is skipped entirely.
+func (f metricFilter) bucketsEnabled() bool {
+	for i := range bucketDefs {
+		if f.enabled(&bucketDefs[i].metricDef) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// A metricSink receives the values of metrics defined by a metricDef, and
+// exports them to a monitoring system.
+type metricSink interface {
+	// Observe receives the value of the metric defined by d.  For per-bucket
+	// metrics, bucket is the name of the bucket.  Otherwise, it is empty.
+	// Labels are the values of the labels named by d.Labels.
+	Observe(d *metricDef, bucket string, v float64, labels ...string)
+
+	// Fail reports that the value of the metric defined by d could not be
+	// retrieved.
+	Fail(d *metricDef, err error)
+}
+
+// gatherStats sends the values of the metrics defined by statsDefs and
+// lastTxIDDef which are enabled by f to sink.
+func gatherStats(sink metricSink, f metricFilter, ss statser, txID func() (int, error)) {
+	s := ss.Stats()
+	for i := range statsDefs {
+		d := &statsDefs[i]
+		if f.enabled(&d.metricDef) {
+			sink.Observe(&d.metricDef, "", d.Value(s))
+		}
+	}
+
+	if !f.enabled(lastTxIDDef) {
+		return
+	}
+
+	if id, err := txID(); err != nil {
+		sink.Fail(lastTxIDDef, err)
+	} else {
+		sink.Observe(lastTxIDDef, "", float64(id))
+	}
+}
+
+// gatherBuckets walks the buckets of c until all buckets are walked, or ctx
+// is canceled, and sends the values of the metrics defined by bucketDefs and
+// scrapeTruncatedDef which are enabled by f to sink.  If no metrics defined by
+// bucketDefs are enabled, the buckets are not walked.
+func gatherBuckets(ctx context.Context, sink metricSink, f metricFilter, c *bucketStatsCollector) {
+	if !f.bucketsEnabled() {
+		return
+	}
+
+	err := c.walk(ctx, func(b bucketInfo, bc *bucketChange) {
+		for i := range bucketDefs {
+			d := &bucketDefs[i]
+			if f.enabled(&d.metricDef) {
+				sink.Observe(&d.metricDef, b.Name, d.Value(b, bc))
+			}
+		}
+	})
+
+	var truncated float64
+	switch err {
+	case nil:
+	case context.Canceled, context.DeadlineExceeded:
+		truncated = 1
+	default:
+		// Report the error using scrape_truncated, or if it is disabled, the
+		// first enabled bucket metric, so the error is never dropped.
+		d := scrapeTruncatedDef
+		if !f.enabled(d) {
+			d = f.filter(bucketMetricDefs())[0]
+		}
+
+		sink.Fail(d, err)
+		return
+	}
+
+	if f.enabled(scrapeTruncatedDef) {
+		sink.Observe(scrapeTruncatedDef, "", truncated)
+	}
+}
+
+var _ metricSink = &promSink{}
+
+// A promSink is a metricSink which sends Prometheus metrics to a channel.
+type promSink struct {
+	ch    chan<- prometheus.Metric
+	name  string
+	descs map[*metricDef]*prometheus.Desc
+}
+
+// newPromDescs creates Prometheus descriptors for each metricDef, named using
+// n.  Each descriptor has a "database" label, a "bucket" label for per-bucket
+// metrics, and the labels named by the metricDef.
+func newPromDescs(n Naming, defs ...*metricDef) map[*metricDef]*prometheus.Desc {
+	descs := make(map[*metricDef]*prometheus.Desc, len(defs))
+	for _, d := range defs {
+		labels := []string{"database"}
+		if d.Bucket {
+			labels = append(labels, "bucket")
+		}
+		labels = append(labels, d.Labels...)
+
+		descs[d] = prometheus.NewDesc(n.fqName(d.Subsystem, d.Name), d.Help, labels, n.ConstLabels)
+	}
+
+	return descs
+}
+
+// Observe implements metricSink.
+func (s *promSink) Observe(d *metricDef, bucket string, v float64, labels ...string) {
+	vt := prometheus.GaugeValue
+	if d.Counter {
+		vt = prometheus.CounterValue
+	}
+
+	values := []string{s.name}
+	if d.Bucket {
+		values = append(values, bucket)
+	}
+	values = append(values, labels...)
+
+	s.ch <- prometheus.MustNewConstMetric(s.descs[d], vt, v, values...)
+}
+
+// Fail implements metricSink.
+func (s *promSink) Fail(d *metricDef, err error) {
+	s.ch <- prometheus.NewInvalidMetric(s.descs[d], err)
+}
@@ -0,0 +1,48 @@
+//go:build linux
+// +build linux
+
+package prombolt
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// mmapSize retrieves the size of the memory mapping which begins at addr,
+// such as a Bolt database's memory map, whose size Bolt does not expose.  The
+// mapping is found using /proc/self/maps.  If the size cannot be retrieved,
+// -1 is returned.
+func mmapSize(addr uintptr) int {
+	f, err := os.Open("/proc/self/maps")
+	if err != nil {
+		return -1
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// Each line begins with the hexadecimal address range of a mapping,
+		// such as "7f3a1c000000-7f3a1c008000".
+		r, _, _ := strings.Cut(s.Text(), " ")
+		start, end, ok := strings.Cut(r, "-")
+		if !ok {
+			continue
+		}
+
+		lo, err := strconv.ParseUint(start, 16, 64)
+		if err != nil || uintptr(lo) != addr {
+			continue
+		}
+
+		hi, err := strconv.ParseUint(end, 16, 64)
+		if err != nil || hi < lo {
+			return -1
+		}
+
+		return int(hi - lo)
+	}
+
+	return -1
+}
@@ -0,0 +1,31 @@
+//go:build linux
+// +build linux
+
+package prombolt
+
+import (
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestMmapSize(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	err := db.View(func(_ *bolt.Tx) error {
+		// Bolt maps a new database using its minimum memory map size.
+		if want, got := 32*1024, mmapSize(db.Info().Data); want != got {
+			t.Fatalf("unexpected memory map size:\n- want: %v\n-  got: %v", want, got)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to view database: %v", err)
+	}
+
+	if want, got := -1, mmapSize(1); want != got {
+		t.Fatalf("unexpected size for unknown mapping:\n- want: %v\n-  got: %v", want, got)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package prombolt
+
+// mmapSize is not supported on this platform, so -1 is always returned.
+func mmapSize(_ uintptr) int {
+	return -1
+}
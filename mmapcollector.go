@@ -0,0 +1,103 @@
+package prombolt
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ prometheus.Collector = &mmapCollector{}
+
+// An mmapCollector is a prometheus.Collector for Bolt database memory map and
+// file growth events.
+type mmapCollector struct {
+	name  string
+	state func() (mmapState, error)
+
+	// prev is the state observed in the previous collection, and is
+	// protected by the mutex of the collector which owns the mmapCollector.
+	prev    *mmapState
+	remaps  int
+	growths int
+
+	filter metricFilter
+	descs  map[*metricDef]*prometheus.Desc
+}
+
+// An mmapState is the state of a Bolt database's memory map and file at a
+// point in time.
+type mmapState struct {
+	// Data is the base address of the memory map.
+	Data uintptr
+	// MmapSize is the size of the memory map in bytes, or -1 if unknown,
+	// such as on platforms other than Linux.
+	MmapSize int
+	// FileSize is the size of the database file in bytes.
+	FileSize int64
+}
+
+// newMmapCollector creates a new mmapCollector with the specified name,
+// function for retrieving the memory map state, filter for enabled metrics,
+// and metric naming.
+func newMmapCollector(name string, state func() (mmapState, error), f metricFilter, n Naming) *mmapCollector {
+	return &mmapCollector{
+		name:   name,
+		state:  state,
+		filter: f,
+		descs:  newPromDescs(n, f.filter(mmapMetricDefs)...),
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *mmapCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *mmapCollector) Collect(ch chan<- prometheus.Metric) {
+	c.gather(&promSink{
+		ch:    ch,
+		name:  c.name,
+		descs: c.descs,
+	})
+}
+
+// gather sends the values of the metrics defined by mmapMetricDefs which are
+// enabled to sink.
+func (c *mmapCollector) gather(sink metricSink) {
+	defs := c.filter.filter(mmapMetricDefs)
+	if len(defs) == 0 {
+		return
+	}
+
+	s, err := c.state()
+	if err != nil {
+		sink.Fail(defs[0], err)
+		return
+	}
+
+	if c.prev != nil {
+		// The memory map may be remapped at the same base address, so a change
+		// in size is also considered a remap.
+		if s.Data != c.prev.Data || s.MmapSize != c.prev.MmapSize {
+			c.remaps++
+		}
+
+		if s.FileSize > c.prev.FileSize {
+			c.growths++
+		}
+	}
+	c.prev = &s
+
+	if c.filter.enabled(mmapRemapsTotalDef) {
+		sink.Observe(mmapRemapsTotalDef, "", float64(c.remaps))
+	}
+
+	if c.filter.enabled(fileGrowEventsTotalDef) {
+		sink.Observe(fileGrowEventsTotalDef, "", float64(c.growths))
+	}
+
+	if s.MmapSize >= 0 && c.filter.enabled(mmapSizeBytesDef) {
+		sink.Observe(mmapSizeBytesDef, "", float64(s.MmapSize))
+	}
+}
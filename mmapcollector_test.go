@@ -0,0 +1,86 @@
+package prombolt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMmapCollector(t *testing.T) {
+	var s mmapState
+	mc := newMmapCollector("test.db", func() (mmapState, error) {
+		return s, nil
+	}, metricFilter{}, Naming{})
+
+	tests := []struct {
+		name    string
+		s       mmapState
+		matches []string
+	}{
+		{
+			name: "initial",
+			s: mmapState{
+				Data:     1,
+				MmapSize: 32768,
+				FileSize: 32768,
+			},
+			matches: []string{
+				`bolt_db_mmap_remaps_total{database="test.db"} 0`,
+				`bolt_db_file_grow_events_total{database="test.db"} 0`,
+				`bolt_db_mmap_size_bytes{database="test.db"} 32768`,
+			},
+		},
+		{
+			name: "file grown",
+			s: mmapState{
+				Data:     1,
+				MmapSize: 32768,
+				FileSize: 65536,
+			},
+			matches: []string{
+				`bolt_db_mmap_remaps_total{database="test.db"} 0`,
+				`bolt_db_file_grow_events_total{database="test.db"} 1`,
+				`bolt_db_mmap_size_bytes{database="test.db"} 32768`,
+			},
+		},
+		{
+			name: "remapped same address",
+			s: mmapState{
+				Data:     1,
+				MmapSize: 65536,
+				FileSize: 65536,
+			},
+			matches: []string{
+				`bolt_db_mmap_remaps_total{database="test.db"} 1`,
+				`bolt_db_file_grow_events_total{database="test.db"} 1`,
+				`bolt_db_mmap_size_bytes{database="test.db"} 65536`,
+			},
+		},
+		{
+			name: "remapped new address",
+			s: mmapState{
+				Data:     2,
+				MmapSize: 65536,
+				FileSize: 65536,
+			},
+			matches: []string{
+				`bolt_db_mmap_remaps_total{database="test.db"} 2`,
+				`bolt_db_file_grow_events_total{database="test.db"} 1`,
+				`bolt_db_mmap_size_bytes{database="test.db"} 65536`,
+			},
+		},
+	}
+
+	// Each test case depends on the state left by the previous one.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s = tt.s
+			got := testCollector(t, mc)
+
+			for _, m := range tt.matches {
+				if !strings.Contains(got, m) {
+					t.Fatalf("output did not contain expected metric: %q", m)
+				}
+			}
+		})
+	}
+}
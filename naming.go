@@ -0,0 +1,73 @@
+package prombolt
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// A Naming configures the names and constant labels of the Prometheus metrics
+// produced by a collector created by New, or by a DB.  The zero value uses
+// the default names, and adds no constant labels.
+type Naming struct {
+	// Namespace replaces the default "bolt" namespace of each metric name.
+	// If empty, the default namespace is used.
+	Namespace string
+
+	// Subsystems maps the default subsystems of metric names, such as "db",
+	// "tx", "write_tx", and "bucket", to replacement subsystems.  Subsystems
+	// which are not present in the map are unchanged.
+	Subsystems map[string]string
+
+	// ConstLabels are added to every metric, such as to identify a service,
+	// shard, or role.  ConstLabels must not use the names of labels already
+	// used by a metric, such as "database".
+	ConstLabels prometheus.Labels
+}
+
+// MetricNaming sets the names and constant labels of the metrics produced by
+// a collector.
+//
+// MetricNaming only applies to Prometheus metrics, so the names of Samples
+// and OpenTelemetry instruments are unchanged.  Metric names passed to
+// DisableMetrics and groups passed to DisableGroups may use either the
+// default names or the configured names.
+//
+// By default, the namespace "bolt" and the default subsystems are used, and
+// no constant labels are added.
+func MetricNaming(n Naming) Option {
+	return func(c *config) {
+		c.naming = n
+	}
+}
+
+// DBMetricNaming is like MetricNaming, but sets the names and constant labels
+// of the metrics produced by a DB.  Typically, it should be passed the same
+// Naming passed to MetricNaming.
+func DBMetricNaming(n Naming) DBOption {
+	return func(c *dbConfig) {
+		c.naming = n
+	}
+}
+
+// namespace returns the namespace of metric names.
+func (n Naming) namespace() string {
+	if n.Namespace == "" {
+		return namespace
+	}
+
+	return n.Namespace
+}
+
+// subsystem returns the subsystem which replaces the default subsystem s.
+func (n Naming) subsystem(s string) string {
+	if r, ok := n.Subsystems[s]; ok {
+		return r
+	}
+
+	return s
+}
+
+// fqName returns the fully-qualified name of a metric with the default
+// subsystem and name.
+func (n Naming) fqName(subsystem, name string) string {
+	return prometheus.BuildFQName(n.namespace(), n.subsystem(subsystem), name)
+}
@@ -0,0 +1,140 @@
+package prombolt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNaming(t *testing.T) {
+	n := Naming{
+		Namespace: "kv",
+		Subsystems: map[string]string{
+			"bucket": "table",
+		},
+		ConstLabels: prometheus.Labels{
+			"service": "users",
+			"shard":   "1",
+			"role":    "primary",
+		},
+	}
+
+	bdb, done := testDB(t)
+	defer done()
+
+	db := NewDB("test.db", bdb, DBMetricNaming(n))
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := db.WrapTx(tx).CreateBucket([]byte("foo"))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte("key"), []byte("value"))
+	})
+	if err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		c       prometheus.Collector
+		matches []string
+	}{
+		{
+			name: "collector",
+			c:    New("test.db", bdb, MetricNaming(n)),
+			matches: []string{
+				`kv_db_freelist_free_pages{database="test.db",role="primary",service="users",shard="1"}`,
+				`kv_db_mmap_size_bytes{database="test.db",role="primary",service="users",shard="1"}`,
+				`kv_tx_writes_total{database="test.db",role="primary",service="users",shard="1"}`,
+				`kv_table_keys{bucket="foo",database="test.db",role="primary",service="users",shard="1"} 1`,
+				`kv_scrape_truncated{database="test.db",role="primary",service="users",shard="1"} 0`,
+			},
+		},
+		{
+			name: "DB",
+			c:    db,
+			matches: []string{
+				`kv_tx_duration_seconds_count{database="test.db",operation="unknown",role="primary",rw="true",service="users",shard="1"} 1`,
+				`kv_write_tx_pages_allocated_count{database="test.db",operation="unknown",role="primary",service="users",shard="1"} 1`,
+				`kv_table_written_bytes_total{bucket="foo",database="test.db",role="primary",service="users",shard="1"} 8`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testCollector(t, tt.c)
+
+			for _, m := range tt.matches {
+				if !strings.Contains(got, m) {
+					t.Fatalf("output did not contain expected metric: %q", m)
+				}
+			}
+
+			for _, l := range strings.Split(got, "\n") {
+				if strings.HasPrefix(l, "bolt_") {
+					t.Fatalf("output contained metric with default namespace: %q", l)
+				}
+
+				if strings.HasPrefix(l, "kv_") && !strings.Contains(l, `role="primary"`) {
+					t.Fatalf("output contained metric without constant labels: %q", l)
+				}
+			}
+		})
+	}
+}
+
+func TestNamingDisable(t *testing.T) {
+	n := Naming{
+		Namespace: "store",
+		Subsystems: map[string]string{
+			"bucket": "table",
+		},
+	}
+
+	db, done := testDB(t)
+	defer done()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("foo"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	// Configured and default names may both be used to disable metrics.
+	got := testCollector(t, New("test.db", db,
+		MetricNaming(n),
+		DisableGroups("table"),
+		DisableMetrics("store_db_info", "bolt_tx_writes_total"),
+	))
+
+	present := []string{
+		"store_db_freelist_free_pages",
+		"store_tx_cursors_total",
+	}
+
+	for _, p := range present {
+		if !strings.Contains(got, p) {
+			t.Fatalf("output did not contain expected metric: %q", p)
+		}
+	}
+
+	absent := []string{
+		"store_table_",
+		"store_scrape_truncated",
+		"store_db_info",
+		"store_tx_writes_total",
+	}
+
+	for _, a := range absent {
+		if strings.Contains(got, a) {
+			t.Fatalf("output contained unexpected metric: %q", a)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package prombolt
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// opUnknown is the operation label value used for transactions with no
+	// operation name attached to their context.
+	opUnknown = "unknown"
+
+	// opOther is the operation label value used for transactions whose
+	// operation name is not permitted, to keep the set of label values
+	// bounded.
+	opOther = "other"
+
+	// maxOperations is the maximum number of distinct operation names used
+	// as label values when no operation names are specified using Operations.
+	maxOperations = 64
+)
+
+// An opKey is the context key for an operation name.
+type opKey struct{}
+
+// WithOp returns a copy of ctx with an operation name attached, such as
+// "enqueue_job" or "gc_sessions".  When ctx is passed to a DB's ViewContext,
+// UpdateContext, or BatchContext methods, the operation name is added as a
+// label to the metrics recorded for the transaction.
+func WithOp(ctx context.Context, op string) context.Context {
+	return context.WithValue(ctx, opKey{}, op)
+}
+
+// opFromContext retrieves the operation name attached to ctx by WithOp, if
+// one is present.
+func opFromContext(ctx context.Context) string {
+	op, _ := ctx.Value(opKey{}).(string)
+	return op
+}
+
+// A DBOption is a functional option which configures a DB created by NewDB.
+type DBOption func(c *dbConfig)
+
+// Operations sets the operation names which may be used as label values for
+// a DB's metrics.  Transactions with any other operation name attached to
+// their context are labeled "other".
+//
+// By default, the first 64 distinct operation names are used as label values,
+// and any others are labeled "other".
+func Operations(ops ...string) DBOption {
+	return func(c *dbConfig) {
+		c.ops = ops
+	}
+}
+
+// dbConfig contains the configuration for a DB, set using DBOptions.
+type dbConfig struct {
+	ops     []string
+	buckets []string
+
+	slowThreshold time.Duration
+	slowHook      func(SlowTx)
+
+	tracerProvider trace.TracerProvider
+
+	naming Naming
+}
+
+// operation returns the operation label value for a transaction using ctx.
+func (db *DB) operation(ctx context.Context) string {
+	op := opFromContext(ctx)
+	if op == "" {
+		return opUnknown
+	}
+
+	db.opsMu.Lock()
+	defer db.opsMu.Unlock()
+
+	if _, ok := db.ops[op]; ok {
+		return op
+	}
+
+	if db.opsFixed || len(db.ops) >= maxOperations {
+		return opOther
+	}
+
+	db.ops[op] = struct{}{}
+	return op
+}
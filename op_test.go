@@ -0,0 +1,141 @@
+package prombolt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestDBOperation(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []string
+		seen int
+		op   string
+		want string
+	}{
+		{
+			name: "no operation",
+			want: opUnknown,
+		},
+		{
+			name: "operation",
+			op:   "gc",
+			want: "gc",
+		},
+		{
+			name: "allowed operation",
+			ops:  []string{"enqueue_job", "gc"},
+			op:   "gc",
+			want: "gc",
+		},
+		{
+			name: "disallowed operation",
+			ops:  []string{"enqueue_job"},
+			op:   "gc",
+			want: opOther,
+		},
+		{
+			name: "too many operations",
+			seen: maxOperations,
+			op:   "gc",
+			want: opOther,
+		},
+		{
+			name: "previously seen operation",
+			seen: maxOperations,
+			op:   "op0",
+			want: "op0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []DBOption
+			if len(tt.ops) > 0 {
+				options = append(options, Operations(tt.ops...))
+			}
+
+			db := NewDB("test.db", nil, options...)
+
+			for i := 0; i < tt.seen; i++ {
+				_ = db.operation(WithOp(context.Background(), fmt.Sprintf("op%d", i)))
+			}
+
+			ctx := context.Background()
+			if tt.op != "" {
+				ctx = WithOp(ctx, tt.op)
+			}
+
+			if want, got := tt.want, db.operation(ctx); want != got {
+				t.Fatalf("unexpected operation:\n- want: %q\n-  got: %q", want, got)
+			}
+		})
+	}
+}
+
+func TestDBContext(t *testing.T) {
+	bdb, done := testDB(t)
+	defer done()
+
+	db := NewDB("test.db", bdb)
+
+	ctx := WithOp(context.Background(), "gc")
+
+	err := db.UpdateContext(ctx, func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("foo"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+
+	err = db.BatchContext(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("foo")).Put([]byte("foo"), []byte("bar"))
+	})
+	if err != nil {
+		t.Fatalf("failed to batch update database: %v", err)
+	}
+
+	err = db.ViewContext(ctx, func(tx *bolt.Tx) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to view database: %v", err)
+	}
+
+	if err := db.View(func(tx *bolt.Tx) error { return nil }); err != nil {
+		t.Fatalf("failed to view database: %v", err)
+	}
+
+	// Transactions are not begun if the context is already canceled.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	err = db.UpdateContext(canceled, func(tx *bolt.Tx) error {
+		t.Fatal("transaction should not have begun")
+		return nil
+	})
+	if want, got := context.Canceled, err; want != got {
+		t.Fatalf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	got := testCollector(t, db)
+
+	matches := []string{
+		`bolt_tx_duration_seconds_count{database="test.db",operation="gc",rw="true"} 2`,
+		`bolt_tx_duration_seconds_count{database="test.db",operation="gc",rw="false"} 1`,
+		`bolt_tx_duration_seconds_count{database="test.db",operation="unknown",rw="false"} 1`,
+		`bolt_write_tx_pages_allocated_count{database="test.db",operation="gc"} 2`,
+		`bolt_write_tx_total{database="test.db",outcome="commit"} 2`,
+	}
+
+	for _, m := range matches {
+		if !strings.Contains(got, m) {
+			t.Fatalf("output did not contain expected metric: %q", m)
+		}
+	}
+}
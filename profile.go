@@ -0,0 +1,27 @@
+package prombolt
+
+import (
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+)
+
+// opBucketStats is the operation name used to attribute the cost of the
+// bucket statistics walk performed by the collector returned by New.
+const opBucketStats = "prombolt_bucket_stats"
+
+// profileTx calls fn with runtime/pprof labels which identify a transaction
+// set for the calling goroutine, and within a runtime/trace region of the
+// specified type.  Goroutines started by fn inherit the labels.
+func profileTx(ctx context.Context, name, op string, rw bool, region string, fn func()) {
+	labels := pprof.Labels(
+		"database", name,
+		"operation", op,
+		"rw", strconv.FormatBool(rw),
+	)
+
+	pprof.Do(ctx, labels, func(ctx context.Context) {
+		trace.WithRegion(ctx, region, fn)
+	})
+}
@@ -0,0 +1,103 @@
+package prombolt
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestDBProfileLabels(t *testing.T) {
+	bdb, done := testDB(t)
+	defer done()
+
+	db := NewDB("test.db", bdb)
+	ctx := WithOp(context.Background(), "gc")
+
+	tests := []struct {
+		name string
+		fn   func(fn func(*bolt.Tx) error) error
+		rw   string
+	}{
+		{
+			name: "update",
+			fn: func(fn func(*bolt.Tx) error) error {
+				return db.UpdateContext(ctx, fn)
+			},
+			rw: "true",
+		},
+		{
+			name: "batch",
+			fn: func(fn func(*bolt.Tx) error) error {
+				return db.BatchContext(ctx, fn)
+			},
+			rw: "true",
+		},
+		{
+			name: "view",
+			fn: func(fn func(*bolt.Tx) error) error {
+				return db.ViewContext(ctx, fn)
+			},
+			rw: "false",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var profile string
+			err := tt.fn(func(_ *bolt.Tx) error {
+				profile = goroutineProfile(t)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("failed to perform transaction: %v", err)
+			}
+
+			want := `"database":"test.db", "operation":"gc", "rw":"` + tt.rw + `"`
+			if !strings.Contains(profile, want) {
+				t.Fatalf("profile did not contain expected labels: %q", want)
+			}
+		})
+	}
+}
+
+func TestForEachWithBoltDBProfileLabels(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("foo"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	var profile string
+	err = forEachWithBoltDB("test.db", db, 1)(context.Background(), func(_ bucketInfo) error {
+		profile = goroutineProfile(t)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk buckets: %v", err)
+	}
+
+	want := `"database":"test.db", "operation":"prombolt_bucket_stats", "rw":"false"`
+	if !strings.Contains(profile, want) {
+		t.Fatalf("profile did not contain expected labels: %q", want)
+	}
+}
+
+// goroutineProfile returns a goroutine profile, which includes the
+// runtime/pprof labels of each goroutine.
+func goroutineProfile(t *testing.T) string {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		t.Fatalf("failed to write goroutine profile: %v", err)
+	}
+
+	return buf.String()
+}
@@ -2,14 +2,17 @@
 package prombolt
 
 import (
+	"context"
+	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
-	// namespace is the top-level namespace metric names.
+	// namespace is the default top-level namespace of metric names.
 	namespace = "bolt"
 )
 
@@ -18,37 +21,275 @@ const (
 //
 // Name should specify a unique name for the collector, and will be added
 // as a label to all produced Prometheus metrics.
-func New(name string, db *bolt.DB) prometheus.Collector {
-	return &collector{
-		stats:       newStatsCollector(name, db),
-		bucketStats: newBucketStatsCollector(name, db),
+//
+// Zero or more Options may be specified to configure the collector.
+func New(name string, db *bolt.DB, options ...Option) prometheus.Collector {
+	return newCollector(name, db, options...)
+}
+
+// newCollector creates a new collector, which is exposed as a
+// prometheus.Collector by New.
+func newCollector(name string, db *bolt.DB, options ...Option) *collector {
+	return newCollectorConfig(name, db, newConfig(options...))
+}
+
+// newConfig creates a config with the defaults, and applies options to it.
+// Unknown groups and metric names passed to DisableGroups and DisableMetrics
+// are logged.
+func newConfig(options ...Option) *config {
+	cfg := &config{
+		workers: 1,
+	}
+
+	for _, o := range options {
+		o(cfg)
+	}
+
+	// The filter matches names configured by MetricNaming regardless of the
+	// order of Options.
+	cfg.filter.naming = cfg.naming
+
+	groups, metrics := cfg.filter.unknown()
+	for _, g := range groups {
+		slog.Default().LogAttrs(context.Background(), slog.LevelWarn, "ignoring unknown Bolt metric group",
+			slog.String("group", g),
+		)
+	}
+	for _, m := range metrics {
+		slog.Default().LogAttrs(context.Background(), slog.LevelWarn, "ignoring unknown Bolt metric",
+			slog.String("metric", m),
+		)
+	}
+
+	return cfg
+}
+
+// newCollectorConfig creates a new collector using cfg.
+func newCollectorConfig(name string, db *bolt.DB, cfg *config) *collector {
+	// The transaction ID, page size, and memory map state are retrieved within
+	// a single read-only transaction for each collection.
+	snapshot := &snapshotter{take: snapshotWithBoltDB(db)}
+
+	c := &collector{
+		name:        name,
+		timeout:     cfg.timeout,
+		snapshot:    snapshot,
+		info:        newInfoCollector(name, db, snapshot.pageSize, cfg.filter, cfg.naming),
+		stats:       newStatsCollector(name, db, snapshot.txID, cfg.filter, cfg.naming),
+		mmap:        newMmapCollector(name, snapshot.mmapState, cfg.filter, cfg.naming),
+		bucketStats: newBucketStatsCollector(name, db, cfg.workers, cfg.filter, cfg.naming),
+	}
+
+	if cfg.residency && residencySupported {
+		c.residency = newResidencyCollector(name, residencyWithPath(db.Path()), cfg.filter, cfg.naming)
+	}
+
+	if filesystemSupported {
+		c.filesystem = newFilesystemCollector(name, filesystemStatsWithBoltDB(db), cfg.filter, cfg.naming)
+	}
+
+	c.descs = c.promDescs()
+	return c
+}
+
+// An Option is a functional option which configures a collector created
+// by New.
+type Option func(c *config)
+
+// Workers sets the number of goroutines used to compute bucket statistics
+// concurrently within a single read-only transaction.  Metrics are still
+// emitted in the order in which Bolt returns each bucket.
+//
+// By default, a single goroutine computes statistics for each bucket in turn.
+// If n is less than 1, it is treated as 1.
+func Workers(n int) Option {
+	return func(c *config) {
+		if n < 1 {
+			n = 1
+		}
+
+		c.workers = n
+	}
+}
+
+// Timeout sets a deadline for each metrics collection.  When the deadline is
+// exceeded, the bucket statistics walk stops between buckets, metrics for the
+// buckets already walked are emitted, and the bolt_scrape_truncated metric is
+// set to 1.
+//
+// By default, no deadline is set.  If d is less than or equal to zero, no
+// deadline is set.
+func Timeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// PageCacheResidency enables collection of the number of bytes of the
+// database file which are resident in the operating system's page cache,
+// using mincore(2).  Each collection maps the entire database file into
+// memory, so this may be expensive for very large databases.
+//
+// By default, page cache residency is not collected.  PageCacheResidency is
+// only supported on Linux, and has no effect on other platforms.
+func PageCacheResidency() Option {
+	return func(c *config) {
+		c.residency = true
 	}
 }
 
+// DisableGroups disables all metrics in the specified groups, which are named
+// after the metric subsystems:
+//   - "db": database metrics, such as bolt_db_freelist_free_pages
+//   - "tx": transaction metrics, such as bolt_tx_writes_total
+//   - "bucket": bucket metrics, such as bolt_bucket_keys
+//
+// Groups may also be named using the subsystems configured by MetricNaming.
+// When the "bucket" group is disabled, the bucket statistics walk is skipped
+// entirely, and bolt_scrape_truncated is not reported.  Unknown groups are
+// ignored, and logged at the warn level using slog.Default.
+//
+// By default, all groups are enabled.
+func DisableGroups(groups ...string) Option {
+	return func(c *config) {
+		if c.filter.groups == nil {
+			c.filter.groups = make(map[string]bool)
+		}
+
+		for _, g := range groups {
+			c.filter.groups[g] = true
+		}
+	}
+}
+
+// DisableMetrics disables individual metrics by their fully-qualified names,
+// such as "bolt_tx_nodes_dereferenced_total" or "bolt_db_mmap_size_bytes".
+// Metrics may be named using either their default names, or the names
+// configured by MetricNaming.
+//
+// If every bucket metric is disabled, the bucket statistics walk is skipped
+// entirely, as if the "bucket" group were disabled.  Unknown metric names are
+// ignored, and logged at the warn level using slog.Default.
+//
+// By default, all metrics are enabled.
+func DisableMetrics(names ...string) Option {
+	return func(c *config) {
+		if c.filter.metrics == nil {
+			c.filter.metrics = make(map[string]bool)
+		}
+
+		for _, n := range names {
+			c.filter.metrics[n] = true
+		}
+	}
+}
+
+// config contains the configuration for a collector, set using Options.
+type config struct {
+	workers   int
+	timeout   time.Duration
+	residency bool
+	filter    metricFilter
+	naming    Naming
+
+	reportError   func(err error)
+	textfileError func(err error)
+}
+
 // Enforce that collector is a prometheus.Collector.
 var _ prometheus.Collector = &collector{}
 
-// A collector is a prometheus.Collector for Bolt database metrics.
+// A collector is a prometheus.Collector for Bolt database metrics.  The
+// metrics it gathers are also sent to other metricSinks by Report and
+// RegisterMeter.
 type collector struct {
+	name    string
+	timeout time.Duration
+
+	// descs are the Prometheus descriptors of all enabled metrics.
+	descs map[*metricDef]*prometheus.Desc
+
 	mu          sync.Mutex
+	snapshot    *snapshotter
+	info        *infoCollector
 	stats       *statsCollector
+	mmap        *mmapCollector
+	residency   *residencyCollector
+	filesystem  *filesystemCollector
 	bucketStats *bucketStatsCollector
 }
 
+// promDescs returns the Prometheus descriptors of the enabled metrics of each
+// of c's collectors.
+func (c *collector) promDescs() map[*metricDef]*prometheus.Desc {
+	all := []map[*metricDef]*prometheus.Desc{
+		c.info.descs,
+		c.stats.descs,
+		c.mmap.descs,
+		c.bucketStats.descs,
+	}
+	if c.residency != nil {
+		all = append(all, c.residency.descs)
+	}
+	if c.filesystem != nil {
+		all = append(all, c.filesystem.descs)
+	}
+
+	descs := make(map[*metricDef]*prometheus.Desc)
+	for _, ds := range all {
+		for d, desc := range ds {
+			descs[d] = desc
+		}
+	}
+
+	return descs
+}
+
 // Describe implements the prometheus.Collector interface.
 func (c *collector) Describe(ch chan<- *prometheus.Desc) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.stats.Describe(ch)
-	c.bucketStats.Describe(ch)
+	for _, d := range c.descs {
+		ch <- d
+	}
 }
 
 // Collect implements the prometheus.Collector interface.
 func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(context.Background(), ch)
+}
+
+// collect collects metrics until all metrics are collected, or ctx is canceled.
+// If the collector has a timeout set, it is applied to ctx.
+func (c *collector) collect(ctx context.Context, ch chan<- prometheus.Metric) {
+	c.gather(ctx, &promSink{
+		ch:    ch,
+		name:  c.name,
+		descs: c.descs,
+	})
+}
+
+// gather sends the values of all enabled metrics to sink until all metrics
+// are gathered, or ctx is canceled.  If the collector has a timeout set, it is
+// applied to ctx.
+func (c *collector) gather(ctx context.Context, sink metricSink) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.stats.Collect(ch)
-	c.bucketStats.Collect(ch)
+	c.snapshot.collect(func() {
+		c.info.gather(sink)
+		c.stats.gather(sink)
+		c.mmap.gather(sink)
+		if c.residency != nil {
+			c.residency.gather(sink)
+		}
+		if c.filesystem != nil {
+			c.filesystem.gather(sink)
+		}
+		c.bucketStats.gather(ctx, sink)
+	})
 }
@@ -1,11 +1,17 @@
 package prombolt
 
 import (
+	"bytes"
+	"context"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/boltdb/bolt"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -33,3 +39,225 @@ func testCollector(t *testing.T, collector prometheus.Collector) string {
 
 	return string(buf)
 }
+
+// testDB creates a Bolt database in a temporary file, and returns the database
+// and a function to clean it up.
+func testDB(t *testing.T) (*bolt.DB, func()) {
+	f, err := ioutil.TempFile("", "prombolt")
+	if err != nil {
+		t.Fatalf("failed to create temporary file: %v", err)
+	}
+	_ = f.Close()
+
+	db, err := bolt.Open(f.Name(), 0666, nil)
+	if err != nil {
+		t.Fatalf("failed to open Bolt database: %v", err)
+	}
+
+	return db, func() {
+		_ = db.Close()
+		_ = os.Remove(f.Name())
+	}
+}
+
+func TestCollectorDisable(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		walk    bool
+		present []string
+		absent  []string
+	}{
+		{
+			name: "all enabled",
+			walk: true,
+			present: []string{
+				"bolt_db_info",
+				"bolt_db_freelist_free_pages",
+				"bolt_tx_nodes_dereferenced_total",
+				"bolt_bucket_keys",
+				"bolt_scrape_truncated",
+			},
+		},
+		{
+			name:    "db group",
+			options: []Option{DisableGroups("db")},
+			walk:    true,
+			present: []string{
+				"bolt_tx_writes_total",
+				"bolt_bucket_keys",
+			},
+			absent: []string{
+				"bolt_db_info",
+				"bolt_db_mmap_size_bytes",
+				"bolt_db_freelist_free_pages",
+				"bolt_db_last_txid",
+			},
+		},
+		{
+			name:    "tx and bucket groups",
+			options: []Option{DisableGroups("tx", "bucket")},
+			present: []string{
+				"bolt_db_info",
+				"bolt_db_freelist_free_pages",
+			},
+			absent: []string{
+				"bolt_tx_",
+				"bolt_bucket_",
+				"bolt_scrape_truncated",
+			},
+		},
+		{
+			name: "metrics",
+			options: []Option{DisableMetrics(
+				"bolt_db_info",
+				"bolt_db_mmap_size_bytes",
+				"bolt_tx_nodes_dereferenced_total",
+				"bolt_bucket_changes_total",
+				"bolt_scrape_truncated",
+			)},
+			walk: true,
+			present: []string{
+				"bolt_db_mmap_remaps_total",
+				"bolt_tx_writes_total",
+				"bolt_bucket_keys",
+			},
+			absent: []string{
+				"bolt_db_info",
+				"bolt_db_mmap_size_bytes",
+				"bolt_tx_nodes_dereferenced_total",
+				"bolt_bucket_changes_total",
+				"bolt_scrape_truncated",
+			},
+		},
+		{
+			name: "all bucket metrics",
+			options: func() []Option {
+				var names []string
+				for _, d := range bucketDefs {
+					names = append(names, d.FQName())
+				}
+
+				return []Option{DisableMetrics(names...)}
+			}(),
+			present: []string{
+				"bolt_tx_writes_total",
+			},
+			absent: []string{
+				"bolt_bucket_",
+				"bolt_scrape_truncated",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, done := testDB(t)
+			defer done()
+
+			err := db.Update(func(tx *bolt.Tx) error {
+				_, err := tx.CreateBucket([]byte("foo"))
+				return err
+			})
+			if err != nil {
+				t.Fatalf("failed to create bucket: %v", err)
+			}
+
+			c := newCollector("test.db", db, tt.options...)
+
+			var walked bool
+			forEach := c.bucketStats.forEach
+			c.bucketStats.forEach = func(ctx context.Context, fn forEachBucketStatsFunc) error {
+				walked = true
+				return forEach(ctx, fn)
+			}
+
+			got := testCollector(t, c)
+
+			if want, got := tt.walk, walked; want != got {
+				t.Fatalf("unexpected bucket walk:\n- want: %v\n-  got: %v", want, got)
+			}
+
+			for _, p := range tt.present {
+				if !strings.Contains(got, p) {
+					t.Fatalf("output did not contain expected metric: %q", p)
+				}
+			}
+
+			for _, a := range tt.absent {
+				if strings.Contains(got, a) {
+					t.Fatalf("output contained unexpected metric: %q", a)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectorDisableUnknown(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	_ = New("test.db", db,
+		DisableGroups("db", "foo"),
+		DisableMetrics("bolt_tx_writes_total", "bolt_bar"),
+	)
+
+	got := buf.String()
+
+	for _, want := range []string{
+		`msg="ignoring unknown Bolt metric group" group=foo`,
+		`msg="ignoring unknown Bolt metric" metric=bolt_bar`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("log did not contain expected message: %q\n%s", want, got)
+		}
+	}
+
+	for _, unwanted := range []string{"group=db", "metric=bolt_tx_writes_total"} {
+		if strings.Contains(got, unwanted) {
+			t.Fatalf("log contained unexpected message: %q\n%s", unwanted, got)
+		}
+	}
+}
+
+// newMemoryCollector creates a collector which gathers statistics from s, txID,
+// bs, and fixed memory map and filesystem statistics, rather than from a Bolt
+// database.  Only bolt_db_info is gathered from db.
+func newMemoryCollector(db *bolt.DB, s bolt.Stats, txID int, bs *bucketStatsCollector) *collector {
+	snapshot := &snapshotter{
+		take: func() (dbSnapshot, error) {
+			return dbSnapshot{
+				TxID:     txID,
+				PageSize: 4096,
+				Mmap: mmapState{
+					MmapSize: 32768,
+					FileSize: 16384,
+				},
+			}, nil
+		},
+	}
+
+	c := &collector{
+		name:     "test.db",
+		snapshot: snapshot,
+		info:     newInfoCollector("test.db", db, snapshot.pageSize, metricFilter{}, Naming{}),
+		stats: newStatsCollector("test.db", &memoryStatsCollector{s: s}, snapshot.txID,
+			metricFilter{}, Naming{}),
+		mmap: newMmapCollector("test.db", snapshot.mmapState, metricFilter{}, Naming{}),
+		filesystem: newFilesystemCollector("test.db", func() (filesystemStats, error) {
+			return filesystemStats{
+				FreeBytes: 8192,
+				SizeBytes: 65536,
+				AllocSize: 4096,
+			}, nil
+		}, metricFilter{}, Naming{}),
+		bucketStats: bs,
+	}
+	c.descs = c.promDescs()
+
+	return c
+}
@@ -0,0 +1,163 @@
+package prombolt
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// Push gathers metrics from a Bolt database handle, using the
+// prometheus.Collector returned by New, and pushes them to the Pushgateway
+// at url using the specified job name.
+//
+// Push is intended for batch jobs which open a Bolt database, run briefly,
+// and exit, so it should typically be called just before the job exits.
+//
+// Name is passed to New.  Zero or more PushOptions may be specified to
+// configure the push.
+func Push(url, job, name string, db *bolt.DB, options ...PushOption) error {
+	return newPusher(url, job, name, db, options...).push()
+}
+
+// PushEvery is like Push, but pushes metrics immediately, every interval,
+// and once more when ctx is canceled, using the same collector for each push
+// so that bucket changes are tracked between pushes.
+//
+// Errors encountered while pushing metrics do not stop pushing, so that
+// metrics are pushed again on the next interval, such as after the
+// Pushgateway restarts.  Each error is passed to the function set by
+// PushErrorHandler.  PushEvery returns the error from the final push if it
+// fails, or ctx.Err() otherwise.
+func PushEvery(ctx context.Context, interval time.Duration, url, job, name string, db *bolt.DB, options ...PushOption) error {
+	p := newPusher(url, job, name, db, options...)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err := p.push(); err != nil {
+			p.onError(err)
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			if err := p.push(); err != nil {
+				return err
+			}
+
+			return ctx.Err()
+		}
+	}
+}
+
+// A PushOption is a functional option which configures Push and PushEvery.
+type PushOption func(c *pushConfig)
+
+// PushGrouping adds a label with the specified name and value to the
+// grouping key used for pushed metrics, in addition to the job name.
+func PushGrouping(name, value string) PushOption {
+	return func(c *pushConfig) {
+		c.grouping = append(c.grouping, [2]string{name, value})
+	}
+}
+
+// PushAdd specifies that pushed metrics only replace previously pushed
+// metrics with the same name and grouping key, using HTTP POST.
+//
+// By default, pushed metrics replace all previously pushed metrics with the
+// same grouping key, using HTTP PUT.
+func PushAdd() PushOption {
+	return func(c *pushConfig) {
+		c.add = true
+	}
+}
+
+// PushClient sets the HTTP client used to push metrics.
+//
+// By default, a new http.Client is used.
+func PushClient(client *http.Client) PushOption {
+	return func(c *pushConfig) {
+		c.client = client
+	}
+}
+
+// PushCollectorOptions sets the Options passed to New to create the
+// collector whose metrics are pushed.
+func PushCollectorOptions(options ...Option) PushOption {
+	return func(c *pushConfig) {
+		c.options = options
+	}
+}
+
+// PushErrorHandler sets a function which is called with each error
+// encountered by PushEvery before its final push.
+//
+// By default, errors are logged at the warn level using slog.Default.
+func PushErrorHandler(fn func(err error)) PushOption {
+	return func(c *pushConfig) {
+		c.onError = fn
+	}
+}
+
+// pushConfig contains the configuration for a push, set using PushOptions.
+type pushConfig struct {
+	grouping [][2]string
+	add      bool
+	client   *http.Client
+	options  []Option
+	onError  func(err error)
+}
+
+// A pusher pushes the metrics of a single collector to a Pushgateway.
+type pusher struct {
+	p       *push.Pusher
+	add     bool
+	onError func(err error)
+}
+
+// newPusher creates a pusher for the collector created by New.
+func newPusher(url, job, name string, db *bolt.DB, options ...PushOption) *pusher {
+	cfg := &pushConfig{}
+	for _, o := range options {
+		o(cfg)
+	}
+
+	p := push.New(url, job).Collector(New(name, db, cfg.options...))
+	for _, g := range cfg.grouping {
+		p.Grouping(g[0], g[1])
+	}
+	if cfg.client != nil {
+		p.Client(cfg.client)
+	}
+
+	onError := cfg.onError
+	if onError == nil {
+		onError = func(err error) {
+			slog.Default().LogAttrs(context.Background(), slog.LevelWarn, "failed to push Bolt metrics",
+				slog.String("database", name),
+				slog.String("job", job),
+				slog.Any("error", err),
+			)
+		}
+	}
+
+	return &pusher{
+		p:       p,
+		add:     cfg.add,
+		onError: onError,
+	}
+}
+
+// push pushes metrics once.
+func (p *pusher) push() error {
+	if p.add {
+		return p.p.Add()
+	}
+
+	return p.p.Push()
+}
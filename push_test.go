@@ -0,0 +1,219 @@
+package prombolt
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+func TestPush(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []PushOption
+		method  string
+		path    string
+	}{
+		{
+			name:   "replace",
+			method: http.MethodPut,
+			path:   "/metrics/job/backup",
+		},
+		{
+			name:    "add",
+			options: []PushOption{PushAdd()},
+			method:  http.MethodPost,
+			path:    "/metrics/job/backup",
+		},
+		{
+			name:    "grouping",
+			options: []PushOption{PushGrouping("instance", "foo")},
+			method:  http.MethodPut,
+			path:    "/metrics/job/backup/instance/foo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, done := testDB(t)
+			defer done()
+
+			pg := newTestPushgateway(t)
+			defer pg.Close()
+
+			if err := Push(pg.URL, "backup", "test.db", db, tt.options...); err != nil {
+				t.Fatalf("failed to push metrics: %v", err)
+			}
+
+			reqs := pg.Requests()
+			if want, got := 1, len(reqs); want != got {
+				t.Fatalf("unexpected number of pushes:\n- want: %v\n-  got: %v", want, got)
+			}
+
+			req := reqs[0]
+			if want, got := tt.method, req.method; want != got {
+				t.Fatalf("unexpected method:\n- want: %v\n-  got: %v", want, got)
+			}
+			if want, got := tt.path, req.path; want != got {
+				t.Fatalf("unexpected path:\n- want: %v\n-  got: %v", want, got)
+			}
+			if _, ok := req.families["bolt_db_info"]; !ok {
+				t.Fatal("pushed metrics did not contain bolt_db_info")
+			}
+		})
+	}
+}
+
+func TestPushEvery(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	pg := newTestPushgateway(t)
+	defer pg.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := PushEvery(ctx, 10*time.Millisecond, pg.URL, "backup", "test.db", db)
+	if want, got := context.DeadlineExceeded, err; want != got {
+		t.Fatalf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	// At least the initial and final pushes must have occurred.
+	if n := len(pg.Requests()); n < 2 {
+		t.Fatalf("expected at least 2 pushes, but got: %d", n)
+	}
+}
+
+func TestPushEveryError(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	var (
+		mu    sync.Mutex
+		fail  = true
+		calls int
+	)
+
+	pg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls++
+		if fail {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer pg.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Only pushes before the final push fail.
+	var errs int
+	err := PushEvery(ctx, 10*time.Millisecond, pg.URL, "backup", "test.db", db,
+		PushErrorHandler(func(_ error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			errs++
+			if errs == 2 {
+				fail = false
+			}
+		}),
+	)
+	if want, got := context.DeadlineExceeded, err; want != got {
+		t.Fatalf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if want, got := 2, errs; want != got {
+		t.Fatalf("unexpected number of errors:\n- want: %v\n-  got: %v", want, got)
+	}
+	if n := calls; n < 3 {
+		t.Fatalf("expected at least 3 pushes, but got: %d", n)
+	}
+}
+
+func TestPushError(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	pg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}))
+	defer pg.Close()
+
+	if err := Push(pg.URL, "backup", "test.db", db); err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+}
+
+// A testPushgateway is an httptest stand-in for a Pushgateway, which records
+// each push it receives.
+type testPushgateway struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	reqs []pushRequest
+}
+
+// A pushRequest is a push received by a testPushgateway.
+type pushRequest struct {
+	method   string
+	path     string
+	families map[string]*dto.MetricFamily
+}
+
+func newTestPushgateway(t *testing.T) *testPushgateway {
+	pg := &testPushgateway{}
+
+	pg.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		families := make(map[string]*dto.MetricFamily)
+
+		dec := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+		for {
+			var mf dto.MetricFamily
+			if err := dec.Decode(&mf); err != nil {
+				if err != io.EOF {
+					t.Errorf("failed to decode metric family: %v", err)
+				}
+				break
+			}
+
+			families[mf.GetName()] = &mf
+		}
+
+		pg.mu.Lock()
+		defer pg.mu.Unlock()
+
+		pg.reqs = append(pg.reqs, pushRequest{
+			method:   r.Method,
+			path:     r.URL.Path,
+			families: families,
+		})
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	return pg
+}
+
+// Requests returns the pushes received by pg.
+func (pg *testPushgateway) Requests() []pushRequest {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	return pg.reqs
+}
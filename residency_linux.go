@@ -0,0 +1,78 @@
+//go:build linux
+// +build linux
+
+package prombolt
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// residencySupported indicates if fileResidency is supported on this platform.
+const residencySupported = true
+
+// fileResidency maps the file at path into memory and uses mincore(2) to
+// determine the number of bytes of the file which are resident in the page
+// cache.  It returns the number of resident bytes and the size of the file.
+func fileResidency(path string) (int64, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	size := fi.Size()
+	if size == 0 {
+		return 0, 0, nil
+	}
+
+	b, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer syscall.Munmap(b)
+
+	pageSize := int64(os.Getpagesize())
+	vec := make([]byte, (size+pageSize-1)/pageSize)
+	if err := mincore(b, vec); err != nil {
+		return 0, 0, err
+	}
+
+	var resident int64
+	for i, v := range vec {
+		if v&1 == 0 {
+			continue
+		}
+
+		// The last page may extend past the end of the file.
+		n := pageSize
+		if end := int64(i+1) * pageSize; end > size {
+			n -= end - size
+		}
+
+		resident += n
+	}
+
+	return resident, size, nil
+}
+
+// mincore is a wrapper for mincore(2), which is not provided by package syscall.
+func mincore(b []byte, vec []byte) error {
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_MINCORE,
+		uintptr(unsafe.Pointer(&b[0])),
+		uintptr(len(b)),
+		uintptr(unsafe.Pointer(&vec[0])),
+	)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+//go:build linux
+// +build linux
+
+package prombolt
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFileResidency(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	resident, size, err := fileResidency(db.Path())
+	if err != nil {
+		t.Fatalf("failed to retrieve file residency: %v", err)
+	}
+
+	fi, err := os.Stat(db.Path())
+	if err != nil {
+		t.Fatalf("failed to stat database file: %v", err)
+	}
+
+	if want, got := fi.Size(), size; want != got {
+		t.Fatalf("unexpected file size:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	// The database was just created and read by Bolt, so at least some of it
+	// should be resident in the page cache.
+	if resident <= 0 || resident > size {
+		t.Fatalf("unexpected number of resident bytes: %d", resident)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package prombolt
+
+import (
+	"errors"
+)
+
+// residencySupported indicates if fileResidency is supported on this platform.
+const residencySupported = false
+
+// errResidencyNotSupported is returned by fileResidency on platforms which do
+// not support it.
+var errResidencyNotSupported = errors.New("prombolt: page cache residency not supported on this platform")
+
+// fileResidency is not supported on this platform.
+func fileResidency(_ string) (int64, int64, error) {
+	return 0, 0, errResidencyNotSupported
+}
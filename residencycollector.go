@@ -0,0 +1,82 @@
+package prombolt
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ prometheus.Collector = &residencyCollector{}
+
+// A residencyCollector is a prometheus.Collector for the residency of a Bolt
+// database file in the operating system's page cache.
+type residencyCollector struct {
+	name      string
+	residency func() (resident int64, size int64, err error)
+
+	filter metricFilter
+	descs  map[*metricDef]*prometheus.Desc
+}
+
+// newResidencyCollector creates a new residencyCollector with the specified
+// name, function for retrieving the number of bytes of a database file which
+// are resident in the page cache and the size of the file, filter for enabled
+// metrics, and metric naming.
+func newResidencyCollector(name string, residency func() (int64, int64, error), f metricFilter, n Naming) *residencyCollector {
+	return &residencyCollector{
+		name:      name,
+		residency: residency,
+		filter:    f,
+		descs:     newPromDescs(n, f.filter(residencyMetricDefs)...),
+	}
+}
+
+// residencyWithPath returns a function which retrieves the page cache
+// residency of the file at path.
+func residencyWithPath(path string) func() (int64, int64, error) {
+	return func() (int64, int64, error) {
+		return fileResidency(path)
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *residencyCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *residencyCollector) Collect(ch chan<- prometheus.Metric) {
+	c.gather(&promSink{
+		ch:    ch,
+		name:  c.name,
+		descs: c.descs,
+	})
+}
+
+// gather sends the values of the metrics defined by residencyMetricDefs which
+// are enabled to sink.
+func (c *residencyCollector) gather(sink metricSink) {
+	defs := c.filter.filter(residencyMetricDefs)
+	if len(defs) == 0 {
+		return
+	}
+
+	resident, size, err := c.residency()
+	if err != nil {
+		sink.Fail(defs[0], err)
+		return
+	}
+
+	var ratio float64
+	if size > 0 {
+		ratio = float64(resident) / float64(size)
+	}
+
+	if c.filter.enabled(residentBytesDef) {
+		sink.Observe(residentBytesDef, "", float64(resident))
+	}
+
+	if c.filter.enabled(residentRatioDef) {
+		sink.Observe(residentRatioDef, "", ratio)
+	}
+}
@@ -0,0 +1,48 @@
+package prombolt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResidencyCollector(t *testing.T) {
+	tests := []struct {
+		name     string
+		resident int64
+		size     int64
+		matches  []string
+	}{
+		{
+			name: "empty",
+			matches: []string{
+				`bolt_db_resident_bytes{database="test.db"} 0`,
+				`bolt_db_resident_ratio{database="test.db"} 0`,
+			},
+		},
+		{
+			name:     "partially resident",
+			resident: 1024,
+			size:     4096,
+			matches: []string{
+				`bolt_db_resident_bytes{database="test.db"} 1024`,
+				`bolt_db_resident_ratio{database="test.db"} 0.25`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newResidencyCollector("test.db", func() (int64, int64, error) {
+				return tt.resident, tt.size, nil
+			}, metricFilter{}, Naming{})
+
+			got := testCollector(t, rc)
+
+			for _, m := range tt.matches {
+				if !strings.Contains(got, m) {
+					t.Fatalf("output did not contain expected metric: %q", m)
+				}
+			}
+		})
+	}
+}
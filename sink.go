@@ -0,0 +1,181 @@
+package prombolt
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// A Sample is a single value of a Bolt statistic, gathered for a Sink.
+type Sample struct {
+	// Name is the name of the statistic, which is the same as the name of
+	// the Prometheus metric for the statistic, such as
+	// "bolt_db_freelist_free_pages".
+	Name string
+
+	// Counter reports whether the statistic is a cumulative counter, rather
+	// than a gauge.
+	Counter bool
+
+	// Database is the name of the database, as passed to Report.
+	Database string
+
+	// Bucket is the name of the bucket for per-bucket statistics, or empty
+	// for database statistics.
+	Bucket string
+
+	// Labels contains the labels of statistics which have labels other than
+	// the database and bucket, such as "bolt_db_info".  Otherwise, it is nil.
+	Labels map[string]string
+
+	// Value is the value of the statistic.
+	Value float64
+}
+
+// A Sink writes Bolt statistics to a monitoring system.  Sinks are used by
+// Report and ReportEvery.
+type Sink interface {
+	// WriteSamples writes samples gathered at time t.
+	WriteSamples(ctx context.Context, t time.Time, samples []Sample) error
+}
+
+// Report gathers the statistics of a Bolt database handle which are exported
+// as metrics by the prometheus.Collector returned by New, and writes them to
+// sink.
+//
+// Name is used as the Database field of each Sample.  Options configure the
+// statistics gathered as they do for New, except that MetricNaming has no
+// effect.  The ReportErrorHandler Option applies to ReportEvery.
+func Report(ctx context.Context, sink Sink, name string, db *bolt.DB, options ...Option) error {
+	return newSampler(name, db, options...).report(ctx, sink)
+}
+
+// ReportEvery is like Report, but writes statistics immediately and then
+// every interval until ctx is canceled, so that bucket changes are tracked
+// between writes.
+//
+// Errors encountered while gathering or writing statistics do not stop
+// reporting, so that statistics are written again on the next interval, such
+// as after a monitoring system restarts.  Each error is passed to the
+// function set by ReportErrorHandler.  ReportEvery returns ctx.Err() when ctx
+// is canceled.
+func ReportEvery(ctx context.Context, interval time.Duration, sink Sink, name string, db *bolt.DB, options ...Option) error {
+	s := newSampler(name, db, options...)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err := s.report(ctx, sink); err != nil && ctx.Err() == nil {
+			s.onError(err)
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ReportErrorHandler sets a function which is called with each error
+// encountered by ReportEvery.
+//
+// By default, errors are logged at the warn level using slog.Default.
+func ReportErrorHandler(fn func(err error)) Option {
+	return func(c *config) {
+		c.reportError = fn
+	}
+}
+
+// A sampler gathers Samples from a Bolt database.
+type sampler struct {
+	name    string
+	c       *collector
+	now     func() time.Time
+	onError func(err error)
+}
+
+// newSampler creates a sampler for a Bolt database handle.
+func newSampler(name string, db *bolt.DB, options ...Option) *sampler {
+	cfg := newConfig(options...)
+
+	onError := cfg.reportError
+	if onError == nil {
+		onError = func(err error) {
+			slog.Default().LogAttrs(context.Background(), slog.LevelWarn, "failed to report Bolt statistics",
+				slog.String("database", name),
+				slog.Any("error", err),
+			)
+		}
+	}
+
+	return &sampler{
+		name:    name,
+		c:       newCollectorConfig(name, db, cfg),
+		now:     time.Now,
+		onError: onError,
+	}
+}
+
+// report gathers samples and writes them to sink.
+func (s *sampler) report(ctx context.Context, sink Sink) error {
+	now := s.now()
+
+	samples, err := s.sample(ctx)
+	if err != nil {
+		return err
+	}
+
+	return sink.WriteSamples(ctx, now, samples)
+}
+
+// sample gathers samples until all buckets are walked, or ctx is canceled.
+func (s *sampler) sample(ctx context.Context) ([]Sample, error) {
+	sink := &sampleSink{name: s.name}
+	s.c.gather(ctx, sink)
+
+	if sink.err != nil {
+		return nil, sink.err
+	}
+
+	return sink.samples, nil
+}
+
+var _ metricSink = &sampleSink{}
+
+// A sampleSink is a metricSink which accumulates Samples.
+type sampleSink struct {
+	name    string
+	samples []Sample
+	err     error
+}
+
+// Observe implements metricSink.
+func (s *sampleSink) Observe(d *metricDef, bucket string, v float64, labels ...string) {
+	var lm map[string]string
+	if len(d.Labels) > 0 {
+		lm = make(map[string]string, len(d.Labels))
+		for i, l := range d.Labels {
+			lm[l] = labels[i]
+		}
+	}
+
+	s.samples = append(s.samples, Sample{
+		Name:     d.FQName(),
+		Counter:  d.Counter,
+		Database: s.name,
+		Bucket:   bucket,
+		Labels:   lm,
+		Value:    v,
+	})
+}
+
+// Fail implements metricSink.  Only the first error is retained.
+func (s *sampleSink) Fail(_ *metricDef, err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
@@ -0,0 +1,189 @@
+package prombolt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestSamplerSample(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	bs := newMemoryBucketStatsCollector([]memoryBucketStats{{
+		name: "foo",
+		s:    bolt.BucketStats{KeyN: 2},
+		seq:  3,
+	}})
+
+	bs.now = func() time.Time {
+		return time.Unix(1, 0)
+	}
+
+	s := &sampler{
+		name: "test.db",
+		c: newMemoryCollector(db, bolt.Stats{
+			FreePageN: 1,
+			TxStats: bolt.TxStats{
+				WriteTime: 2 * time.Second,
+			},
+		}, 4, bs),
+	}
+
+	samples, err := s.sample(context.Background())
+	if err != nil {
+		t.Fatalf("failed to gather samples: %v", err)
+	}
+
+	want := len(statsDefs) + len(bucketDefs) + len(infoMetricDefs) +
+		len(mmapMetricDefs) + len(filesystemMetricDefs) + 2
+	if got := len(samples); want != got {
+		t.Fatalf("unexpected number of samples:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	// Samples with labels are not comparable, so samples are compared using
+	// their string representations.
+	got := make(map[string]bool)
+	var info *Sample
+	for i, s := range samples {
+		if s.Name == "bolt_db_info" {
+			info = &samples[i]
+		}
+
+		got[fmt.Sprintf("%+v", s)] = true
+	}
+
+	for _, w := range []Sample{
+		{Name: "bolt_db_freelist_free_pages", Database: "test.db", Value: 1},
+		{Name: "bolt_tx_write_seconds_total", Counter: true, Database: "test.db", Value: 2},
+		{Name: "bolt_db_last_txid", Counter: true, Database: "test.db", Value: 4},
+		{Name: "bolt_db_mmap_remaps_total", Counter: true, Database: "test.db", Value: 0},
+		{Name: "bolt_db_mmap_size_bytes", Database: "test.db", Value: 32768},
+		{Name: "bolt_db_filesystem_free_bytes", Database: "test.db", Value: 8192},
+		{Name: "bolt_db_filesystem_growths_remaining", Database: "test.db", Value: 2},
+		{Name: "bolt_bucket_keys", Database: "test.db", Bucket: "foo", Value: 2},
+		{Name: "bolt_bucket_sequence", Database: "test.db", Bucket: "foo", Value: 3},
+		{Name: "bolt_bucket_last_change_timestamp_seconds", Database: "test.db", Bucket: "foo", Value: 1},
+		{Name: "bolt_bucket_changes_total", Counter: true, Database: "test.db", Bucket: "foo", Value: 0},
+		{Name: "bolt_scrape_truncated", Database: "test.db", Value: 0},
+	} {
+		if !got[fmt.Sprintf("%+v", w)] {
+			t.Fatalf("samples did not contain expected sample: %+v", w)
+		}
+	}
+
+	if info == nil {
+		t.Fatal("samples did not contain bolt_db_info")
+	}
+
+	if want, got := len(infoDef.Labels), len(info.Labels); want != got {
+		t.Fatalf("unexpected number of labels:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	if want, got := "4096", info.Labels["page_size"]; want != got {
+		t.Fatalf("unexpected page_size label:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestReport(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("foo"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	var sink memorySink
+	if err := Report(context.Background(), &sink, "test.db", db); err != nil {
+		t.Fatalf("failed to report statistics: %v", err)
+	}
+
+	if want, got := 1, len(sink.writes); want != got {
+		t.Fatalf("unexpected number of writes:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	var bucket, info bool
+	for _, s := range sink.writes[0] {
+		switch {
+		case s.Name == "bolt_bucket_depth" && s.Bucket == "foo":
+			bucket = true
+		case s.Name == "bolt_db_info":
+			info = true
+		}
+	}
+	if !bucket {
+		t.Fatal("samples did not contain bucket statistics")
+	}
+	if !info {
+		t.Fatal("samples did not contain database information")
+	}
+}
+
+func TestReportEvery(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var sink memorySink
+	err := ReportEvery(ctx, 10*time.Millisecond, &sink, "test.db", db)
+	if want, got := context.DeadlineExceeded, err; want != got {
+		t.Fatalf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	if n := len(sink.writes); n < 2 {
+		t.Fatalf("expected at least 2 writes, but got: %d", n)
+	}
+
+	// Errors from a sink are reported, but do not stop reporting.
+	errFoo := errors.New("foo")
+	sink.err = errFoo
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var errs []error
+	err = ReportEvery(ctx, 10*time.Millisecond, &sink, "test.db", db,
+		ReportErrorHandler(func(err error) {
+			errs = append(errs, err)
+		}),
+	)
+	if want, got := context.DeadlineExceeded, err; want != got {
+		t.Fatalf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	if n := len(errs); n < 2 {
+		t.Fatalf("expected at least 2 errors, but got: %d", n)
+	}
+	for _, err := range errs {
+		if want, got := errFoo, err; want != got {
+			t.Fatalf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+		}
+	}
+}
+
+var _ Sink = &memorySink{}
+
+// A memorySink is a Sink which stores the samples it is given.
+type memorySink struct {
+	writes [][]Sample
+	err    error
+}
+
+func (s *memorySink) WriteSamples(_ context.Context, _ time.Time, samples []Sample) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.writes = append(s.writes, samples)
+	return nil
+}
@@ -0,0 +1,81 @@
+package prombolt
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// A SlowTx describes a transaction which took at least the threshold set
+// using SlowTxHook or SlowTxLogger.
+type SlowTx struct {
+	// Duration is the amount of time taken by the transaction, including
+	// waiting for the transaction to begin.
+	Duration time.Duration
+
+	// Operation is the operation name attached to the transaction's context
+	// using WithOp, or "unknown" if none was attached.
+	Operation string
+
+	// Writable reports whether the transaction was a read-write transaction.
+	Writable bool
+
+	// Stats are the statistics of the transaction.  Stats are empty if the
+	// transaction could not be begun.
+	Stats bolt.TxStats
+
+	// Stack is the formatted stack trace of the goroutine which performed
+	// the transaction.
+	Stack []byte
+}
+
+// SlowTxHook sets a function which is called after any transaction which
+// takes at least threshold.  fn is called synchronously by the goroutine
+// which performed the transaction, so it should return quickly.
+//
+// A threshold of zero or less disables slow transaction reporting.
+func SlowTxHook(threshold time.Duration, fn func(SlowTx)) DBOption {
+	return func(c *dbConfig) {
+		c.slowThreshold = threshold
+		c.slowHook = fn
+	}
+}
+
+// SlowTxLogger is like SlowTxHook, but logs each slow transaction at the warn
+// level using logger.
+func SlowTxLogger(threshold time.Duration, logger *slog.Logger) DBOption {
+	return SlowTxHook(threshold, func(tx SlowTx) {
+		logger.LogAttrs(context.Background(), slog.LevelWarn, "slow Bolt transaction",
+			slog.Duration("duration", tx.Duration),
+			slog.String("operation", tx.Operation),
+			slog.Bool("rw", tx.Writable),
+			slog.Int("pages_allocated", tx.Stats.PageCount),
+			slog.Int("nodes_split", tx.Stats.Split),
+			slog.Int("nodes_spilled", tx.Stats.Spill),
+			slog.Int("writes", tx.Stats.Write),
+			slog.Duration("spill_time", tx.Stats.SpillTime),
+			slog.Duration("write_time", tx.Stats.WriteTime),
+			slog.String("stack", string(tx.Stack)),
+		)
+	})
+}
+
+// reportSlowTx calls db's slow transaction hook for the transaction tx.
+func (db *DB) reportSlowTx(d time.Duration, op string, rw bool, tx *bolt.Tx) {
+	s := SlowTx{
+		Duration:  d,
+		Operation: op,
+		Writable:  rw,
+		Stack:     debug.Stack(),
+	}
+
+	// Bolt leaves the statistics of a closed transaction in place.
+	if tx != nil {
+		s.Stats = tx.Stats()
+	}
+
+	db.slowHook(s)
+}
@@ -0,0 +1,121 @@
+package prombolt
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestDBSlowTxHook(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		calls     int
+	}{
+		{
+			name:      "disabled",
+			threshold: 0,
+		},
+		{
+			name:      "not slow",
+			threshold: time.Hour,
+		},
+		{
+			name:      "slow",
+			threshold: time.Nanosecond,
+			calls:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bdb, done := testDB(t)
+			defer done()
+
+			var txs []SlowTx
+			db := NewDB("test.db", bdb, SlowTxHook(tt.threshold, func(tx SlowTx) {
+				txs = append(txs, tx)
+			}))
+
+			ctx := WithOp(context.Background(), "gc")
+			err := db.UpdateContext(ctx, func(tx *bolt.Tx) error {
+				_, err := tx.CreateBucket([]byte("foo"))
+				return err
+			})
+			if err != nil {
+				t.Fatalf("failed to update database: %v", err)
+			}
+
+			if err := db.View(func(tx *bolt.Tx) error { return nil }); err != nil {
+				t.Fatalf("failed to view database: %v", err)
+			}
+
+			if want, got := tt.calls, len(txs); want != got {
+				t.Fatalf("unexpected number of slow transactions:\n- want: %v\n-  got: %v",
+					want, got)
+			}
+
+			if tt.calls == 0 {
+				return
+			}
+
+			update, view := txs[0], txs[1]
+
+			if want, got := "gc", update.Operation; want != got {
+				t.Fatalf("unexpected operation:\n- want: %q\n-  got: %q", want, got)
+			}
+			if !update.Writable {
+				t.Fatal("update transaction should be writable")
+			}
+			if update.Stats.Write == 0 {
+				t.Fatal("update transaction should have performed writes")
+			}
+			if !bytes.Contains(update.Stack, []byte("TestDBSlowTxHook")) {
+				t.Fatalf("stack did not contain caller:\n%s", update.Stack)
+			}
+
+			if want, got := opUnknown, view.Operation; want != got {
+				t.Fatalf("unexpected operation:\n- want: %q\n-  got: %q", want, got)
+			}
+			if view.Writable {
+				t.Fatal("view transaction should not be writable")
+			}
+		})
+	}
+}
+
+func TestDBSlowTxLogger(t *testing.T) {
+	bdb, done := testDB(t)
+	defer done()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	db := NewDB("test.db", bdb, SlowTxLogger(time.Nanosecond, logger))
+
+	ctx := WithOp(context.Background(), "gc")
+	if err := db.ViewContext(ctx, func(tx *bolt.Tx) error { return nil }); err != nil {
+		t.Fatalf("failed to view database: %v", err)
+	}
+
+	got := buf.String()
+
+	matches := []string{
+		`level=WARN`,
+		`msg="slow Bolt transaction"`,
+		`operation=gc`,
+		`rw=false`,
+		`stack=`,
+	}
+
+	for _, m := range matches {
+		if !strings.Contains(got, m) {
+			t.Fatalf("output did not contain expected text: %q\n%s", m, got)
+		}
+	}
+}
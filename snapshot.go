@@ -0,0 +1,101 @@
+package prombolt
+
+import (
+	"os"
+
+	"github.com/boltdb/bolt"
+)
+
+// A dbSnapshot is the state of a Bolt database retrieved within a single
+// read-only transaction.
+type dbSnapshot struct {
+	TxID     int
+	PageSize int
+	Mmap     mmapState
+}
+
+// snapshotWithBoltDB returns a function which begins a read-only Bolt
+// transaction to retrieve a dbSnapshot.
+func snapshotWithBoltDB(db *bolt.DB) func() (dbSnapshot, error) {
+	return func() (dbSnapshot, error) {
+		var s dbSnapshot
+		err := db.View(func(tx *bolt.Tx) error {
+			// The page size is only available while the database is open, and
+			// the memory map cannot be remapped while a transaction is open.
+			info := db.Info()
+
+			s.TxID = tx.ID()
+			s.PageSize = info.PageSize
+			s.Mmap.Data = info.Data
+			s.Mmap.MmapSize = mmapSize(info.Data)
+
+			fi, err := os.Stat(db.Path())
+			if err != nil {
+				return err
+			}
+
+			s.Mmap.FileSize = fi.Size()
+			return nil
+		})
+
+		return s, err
+	}
+}
+
+// A snapshotter shares a single dbSnapshot between the collectors which use
+// it during a collection, so that each collection begins only one read-only
+// transaction to retrieve it, rather than one for each collector.
+type snapshotter struct {
+	take func() (dbSnapshot, error)
+
+	// The remaining fields are protected by the mutex of the collector which
+	// owns the snapshotter.
+	active bool
+	taken  bool
+	snap   dbSnapshot
+	err    error
+}
+
+// collect calls fn, and shares the dbSnapshot retrieved by the first call to
+// get made by fn with all later calls made by fn.
+func (s *snapshotter) collect(fn func()) {
+	s.active = true
+	defer func() {
+		*s = snapshotter{take: s.take}
+	}()
+
+	fn()
+}
+
+// get retrieves a dbSnapshot, or returns the dbSnapshot already retrieved
+// during the current collection.
+func (s *snapshotter) get() (dbSnapshot, error) {
+	if s.taken {
+		return s.snap, s.err
+	}
+
+	snap, err := s.take()
+	if s.active {
+		s.taken, s.snap, s.err = true, snap, err
+	}
+
+	return snap, err
+}
+
+// txID retrieves the ID of the last committed transaction using get.
+func (s *snapshotter) txID() (int, error) {
+	snap, err := s.get()
+	return snap.TxID, err
+}
+
+// pageSize retrieves the database page size using get.
+func (s *snapshotter) pageSize() (int, error) {
+	snap, err := s.get()
+	return snap.PageSize, err
+}
+
+// mmapState retrieves the memory map state using get.
+func (s *snapshotter) mmapState() (mmapState, error) {
+	snap, err := s.get()
+	return snap.Mmap, err
+}
@@ -0,0 +1,110 @@
+package prombolt
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestCollectorReadTransactions(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	c := newCollector("test.db", db)
+
+	// Only the shared snapshot and the bucket statistics walk begin read-only
+	// transactions during a collection.
+	before := db.Stats().TxN
+	_ = testCollector(t, c)
+
+	if want, got := 2, db.Stats().TxN-before; want != got {
+		t.Fatalf("unexpected number of read transactions:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestSnapshotterCollect(t *testing.T) {
+	var n int
+	s := &snapshotter{
+		take: func() (dbSnapshot, error) {
+			n++
+			return dbSnapshot{TxID: n}, nil
+		},
+	}
+
+	s.collect(func() {
+		for i := 0; i < 3; i++ {
+			if id, _ := s.txID(); id != 1 {
+				t.Fatalf("unexpected transaction ID during collection: %d", id)
+			}
+		}
+	})
+
+	// A new snapshot is retrieved for each collection.
+	s.collect(func() {
+		if id, _ := s.txID(); id != 2 {
+			t.Fatalf("unexpected transaction ID during second collection: %d", id)
+		}
+	})
+
+	if want, got := 2, n; want != got {
+		t.Fatalf("unexpected number of snapshots:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestSnapshotWithBoltDB(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	take := snapshotWithBoltDB(db)
+
+	before, err := take()
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+
+	if want, got := os.Getpagesize(), before.PageSize; want != got {
+		t.Fatalf("unexpected page size:\n- want: %v\n-  got: %v", want, got)
+	}
+	// The memory map size is only known on Linux.
+	if runtime.GOOS == "linux" && before.Mmap.MmapSize <= 0 {
+		t.Fatalf("unexpected memory map size: %d", before.Mmap.MmapSize)
+	}
+
+	// Write enough data to force Bolt to grow the file and remap it.
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("foo"))
+		if err != nil {
+			return err
+		}
+
+		v := make([]byte, 1024)
+		for i := 0; i < 1024; i++ {
+			if err := b.Put([]byte(fmt.Sprintf("key%04d", i)), v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to populate database: %v", err)
+	}
+
+	after, err := take()
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+
+	if want, got := before.TxID+1, after.TxID; want != got {
+		t.Fatalf("unexpected transaction ID:\n- want: %v\n-  got: %v", want, got)
+	}
+	if runtime.GOOS == "linux" && after.Mmap.MmapSize <= before.Mmap.MmapSize {
+		t.Fatalf("memory map did not grow: %d -> %d", before.Mmap.MmapSize, after.Mmap.MmapSize)
+	}
+	if after.Mmap.FileSize <= before.Mmap.FileSize {
+		t.Fatalf("file did not grow: %d -> %d", before.Mmap.FileSize, after.Mmap.FileSize)
+	}
+}
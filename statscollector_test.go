@@ -12,9 +12,11 @@ import (
 func TestStatsCollector(t *testing.T) {
 	tests := []struct {
 		s       bolt.Stats
+		txID    int
 		matches []string
 	}{
 		{
+			txID: 19,
 			s: bolt.Stats{
 				FreePageN:     1,
 				PendingPageN:  2,
@@ -56,12 +58,14 @@ func TestStatsCollector(t *testing.T) {
 				`bolt_tx_nodes_spilled_seconds_total{database="test.db"} 16`,
 				`bolt_tx_writes_total{database="test.db"} 17`,
 				`bolt_tx_write_seconds_total{database="test.db"} 18`,
+				`# TYPE bolt_db_last_txid counter`,
+				`bolt_db_last_txid{database="test.db"} 19`,
 			},
 		},
 	}
 
 	for _, tt := range tests {
-		got := testCollector(t, newMemoryStatsCollector(tt.s))
+		got := testCollector(t, newMemoryStatsCollector(tt.s, tt.txID))
 
 		for _, m := range tt.matches {
 			t.Run(m, func(t *testing.T) {
@@ -73,10 +77,18 @@ func TestStatsCollector(t *testing.T) {
 	}
 }
 
-func newMemoryStatsCollector(s bolt.Stats) prometheus.Collector {
-	return newStatsCollector("test.db", &memoryStatsCollector{
-		s: s,
-	})
+func newMemoryStatsCollector(s bolt.Stats, txID int) prometheus.Collector {
+	return newStatsCollector(
+		"test.db",
+		&memoryStatsCollector{
+			s: s,
+		},
+		func() (int, error) {
+			return txID, nil
+		},
+		metricFilter{},
+		Naming{},
+	)
 }
 
 var _ statser = &memoryStatsCollector{}
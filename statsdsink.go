@@ -0,0 +1,120 @@
+package prombolt
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPacketSize is the maximum number of bytes written to a StatsD or
+// Graphite sink in a single write, so that each write fits in a UDP packet
+// on a typical network.
+const maxPacketSize = 1432
+
+var _ Sink = &statsdSink{}
+
+// A statsdSink is a Sink which writes samples in the StatsD or Graphite
+// plaintext protocols.
+type statsdSink struct {
+	w        io.Writer
+	graphite bool
+}
+
+// NewStatsDSink creates a Sink which writes samples to w in the StatsD
+// protocol, such as to a net.Conn created by net.Dial("udp", addr).  Each
+// sample is written as a gauge, including counters, since StatsD counters
+// are reported as deltas rather than cumulative values.
+//
+// Samples are named hierarchically, as described by NewGraphiteSink.  Samples
+// are written using as many writes as needed so that no single write exceeds
+// the size of a typical UDP packet.  Samples with Labels, such as
+// "bolt_db_info", are not written, since their labels cannot be represented
+// in hierarchical names.
+func NewStatsDSink(w io.Writer) Sink {
+	return &statsdSink{w: w}
+}
+
+// NewGraphiteSink creates a Sink which writes samples to w in the Graphite
+// plaintext protocol, such as to a net.Conn created by net.Dial("tcp", addr).
+//
+// Samples are named hierarchically, using the namespace "bolt", the database
+// name, the bucket name preceded by "bucket" for per-bucket statistics, and
+// finally the remainder of the sample's name.  Characters other than ASCII
+// letters, digits, '-', and '_' in database and bucket names are replaced
+// with '_'.  For example, the sample "bolt_bucket_keys" for the bucket "foo"
+// in the database "app.db" is named "bolt.app_db.bucket.foo.keys".
+//
+// As with NewStatsDSink, no single write exceeds the size of a typical UDP
+// packet, and samples with Labels are not written.
+func NewGraphiteSink(w io.Writer) Sink {
+	return &statsdSink{
+		w:        w,
+		graphite: true,
+	}
+}
+
+// WriteSamples implements Sink.
+func (s *statsdSink) WriteSamples(_ context.Context, t time.Time, samples []Sample) error {
+	ts := strconv.FormatInt(t.Unix(), 10)
+
+	var buf bytes.Buffer
+	for _, sm := range samples {
+		if sm.Labels != nil {
+			continue
+		}
+
+		var line string
+		v := strconv.FormatFloat(sm.Value, 'g', -1, 64)
+		if s.graphite {
+			line = samplePath(sm) + " " + v + " " + ts + "\n"
+		} else {
+			line = samplePath(sm) + ":" + v + "|g\n"
+		}
+
+		if buf.Len() > 0 && buf.Len()+len(line) > maxPacketSize {
+			if _, err := s.w.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+
+		buf.WriteString(line)
+	}
+
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+// samplePath returns the hierarchical name of a sample, as described by
+// NewGraphiteSink.
+func samplePath(s Sample) string {
+	name := strings.TrimPrefix(s.Name, namespace+"_")
+
+	parts := []string{namespace, samplePathEscape(s.Database)}
+	if s.Bucket != "" {
+		name = strings.TrimPrefix(name, "bucket_")
+		parts = append(parts, "bucket", samplePathEscape(s.Bucket))
+	}
+
+	return strings.Join(append(parts, name), ".")
+}
+
+// samplePathEscape replaces characters which are not safe to use in a
+// component of a hierarchical sample name.
+func samplePathEscape(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
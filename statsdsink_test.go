@@ -0,0 +1,138 @@
+package prombolt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatsDSink(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer pc.Close()
+
+	c, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	s := NewStatsDSink(c)
+	if err := s.WriteSamples(context.Background(), time.Unix(1, 0), testSamples); err != nil {
+		t.Fatalf("failed to write samples: %v", err)
+	}
+
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	b := make([]byte, maxPacketSize)
+	n, _, err := pc.ReadFrom(b)
+	if err != nil {
+		t.Fatalf("failed to read packet: %v", err)
+	}
+
+	want := `bolt.test_db.db_freelist_free_pages:1|g
+bolt.test_db.tx_write_seconds_total:0.5|g
+bolt.test_db.bucket.foo_bar_baz_qux.keys:2|g
+`
+
+	if got := string(b[:n]); want != got {
+		t.Fatalf("unexpected packet:\n- want:\n%s\n-  got:\n%s", want, got)
+	}
+}
+
+func TestStatsDSinkPacketSize(t *testing.T) {
+	var samples []Sample
+	for i := 0; i < 100; i++ {
+		samples = append(samples, Sample{
+			Name:     "bolt_bucket_keys",
+			Database: "test.db",
+			Bucket:   fmt.Sprintf("bucket%03d", i),
+			Value:    float64(i),
+		})
+	}
+
+	w := &packetWriter{}
+	s := NewStatsDSink(w)
+	if err := s.WriteSamples(context.Background(), time.Unix(1, 0), samples); err != nil {
+		t.Fatalf("failed to write samples: %v", err)
+	}
+
+	if len(w.packets) < 2 {
+		t.Fatalf("expected multiple packets, but got: %d", len(w.packets))
+	}
+
+	var lines int
+	for _, p := range w.packets {
+		if len(p) > maxPacketSize {
+			t.Fatalf("packet exceeds maximum size: %d", len(p))
+		}
+
+		// Lines must not be split across packets.
+		if !bytes.HasSuffix(p, []byte("\n")) {
+			t.Fatalf("packet does not end with a complete line: %q", p)
+		}
+
+		lines += bytes.Count(p, []byte("\n"))
+	}
+
+	if want, got := len(samples), lines; want != got {
+		t.Fatalf("unexpected number of lines:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestGraphiteSink(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	outC := make(chan string, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			outC <- err.Error()
+			return
+		}
+		defer c.Close()
+
+		b, _ := ioutil.ReadAll(c)
+		outC <- string(b)
+	}()
+
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	s := NewGraphiteSink(c)
+	if err := s.WriteSamples(context.Background(), time.Unix(1, 0), testSamples); err != nil {
+		t.Fatalf("failed to write samples: %v", err)
+	}
+	_ = c.Close()
+
+	want := `bolt.test_db.db_freelist_free_pages 1 1
+bolt.test_db.tx_write_seconds_total 0.5 1
+bolt.test_db.bucket.foo_bar_baz_qux.keys 2 1
+`
+
+	if got := <-outC; want != got {
+		t.Fatalf("unexpected output:\n- want:\n%s\n-  got:\n%s", want, got)
+	}
+}
+
+// A packetWriter is an io.Writer which stores each write as a packet.
+type packetWriter struct {
+	packets [][]byte
+}
+
+func (w *packetWriter) Write(b []byte) (int, error) {
+	w.packets = append(w.packets, append([]byte(nil), b...))
+	return len(b), nil
+}
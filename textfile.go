@@ -0,0 +1,86 @@
+package prombolt
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WriteTextfile gathers metrics from a Bolt database handle, using the
+// prometheus.Collector returned by New, and writes them in the Prometheus
+// text format to the file at path.  The file is written atomically, by
+// writing a temporary file in the same directory and renaming it to path.
+//
+// WriteTextfile is intended for use with the node_exporter textfile
+// collector by tools which cannot expose an HTTP endpoint, so path should
+// typically be a file with a ".prom" extension in the textfile collector's
+// directory.
+//
+// Name and options are passed to New.
+func WriteTextfile(path, name string, db *bolt.DB, options ...Option) error {
+	return writeTextfile(path, New(name, db, options...))
+}
+
+// WriteTextfileEvery is like WriteTextfile, but writes metrics immediately
+// and then every interval until ctx is canceled, using the same collector
+// for each write so that bucket changes are tracked between writes.
+//
+// Errors encountered while writing metrics do not stop writing, so that
+// metrics are written again on the next interval, such as after a full
+// filesystem has space again.  Each error is passed to the function set by
+// TextfileErrorHandler.  WriteTextfileEvery returns ctx.Err() when ctx is
+// canceled.
+func WriteTextfileEvery(ctx context.Context, interval time.Duration, path, name string, db *bolt.DB, options ...Option) error {
+	cfg := newConfig(options...)
+	c := newCollectorConfig(name, db, cfg)
+
+	onError := cfg.textfileError
+	if onError == nil {
+		onError = func(err error) {
+			slog.Default().LogAttrs(context.Background(), slog.LevelWarn, "failed to write Bolt metrics textfile",
+				slog.String("database", name),
+				slog.String("path", path),
+				slog.Any("error", err),
+			)
+		}
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err := writeTextfile(path, c); err != nil {
+			onError(err)
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// TextfileErrorHandler sets a function which is called with each error
+// encountered by WriteTextfileEvery.
+//
+// By default, errors are logged at the warn level using slog.Default.
+func TextfileErrorHandler(fn func(err error)) Option {
+	return func(c *config) {
+		c.textfileError = fn
+	}
+}
+
+// writeTextfile gathers metrics from c using a private registry, and writes
+// them to the file at path.
+func writeTextfile(path string, c prometheus.Collector) error {
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(c); err != nil {
+		return err
+	}
+
+	return prometheus.WriteToTextfile(path, reg)
+}
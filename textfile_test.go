@@ -0,0 +1,127 @@
+package prombolt
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestWriteTextfile(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("foo"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+
+	dir := testTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bolt.prom")
+
+	if err := WriteTextfile(path, "test.db", db); err != nil {
+		t.Fatalf("failed to write textfile: %v", err)
+	}
+
+	got := testReadTextfile(t, dir, path)
+
+	want := `bolt_bucket_keys{bucket="foo",database="test.db"} 0`
+	if !strings.Contains(got, want) {
+		t.Fatalf("output did not contain expected metric: %q", want)
+	}
+}
+
+func TestWriteTextfileEvery(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	dir := testTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bolt.prom")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := WriteTextfileEvery(ctx, 10*time.Millisecond, path, "test.db", db)
+	if want, got := context.DeadlineExceeded, err; want != got {
+		t.Fatalf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	got := testReadTextfile(t, dir, path)
+
+	want := `bolt_db_info{`
+	if !strings.Contains(got, want) {
+		t.Fatalf("output did not contain expected metric: %q", want)
+	}
+}
+
+func TestWriteTextfileEveryError(t *testing.T) {
+	db, done := testDB(t)
+	defer done()
+
+	dir := testTempDir(t)
+	defer os.RemoveAll(dir)
+
+	// Writes fail because the directory does not exist, but do not stop
+	// writing on later intervals.
+	path := filepath.Join(dir, "missing", "bolt.prom")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var errs []error
+	err := WriteTextfileEvery(ctx, 10*time.Millisecond, path, "test.db", db,
+		TextfileErrorHandler(func(err error) {
+			errs = append(errs, err)
+		}),
+	)
+	if want, got := context.DeadlineExceeded, err; want != got {
+		t.Fatalf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	if n := len(errs); n < 2 {
+		t.Fatalf("expected at least 2 errors, but got: %d", n)
+	}
+}
+
+// testTempDir creates a temporary directory.
+func testTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "prombolt")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+
+	return dir
+}
+
+// testReadTextfile reads the textfile at path, and verifies that it is the
+// only file in dir.
+func testReadTextfile(t *testing.T, dir, path string) string {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+
+	// Temporary files must be cleaned up.
+	if want, got := 1, len(fis); want != got {
+		t.Fatalf("unexpected number of files:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read textfile: %v", err)
+	}
+
+	return string(b)
+}
@@ -0,0 +1,80 @@
+package prombolt
+
+import (
+	"context"
+
+	"github.com/boltdb/bolt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName is the name of the OpenTelemetry tracer and meter used
+// by prombolt.
+const instrumentationName = "github.com/mdlayher/prombolt"
+
+// TracerProvider sets an OpenTelemetry TracerProvider used to create a span
+// for each transaction performed using a DB's Update, Batch, and View
+// methods, or their context-aware equivalents.  Spans are children of any
+// span in the context passed to the context-aware methods.
+//
+// By default, no spans are created.
+func TracerProvider(tp trace.TracerProvider) DBOption {
+	return func(c *dbConfig) {
+		c.tracerProvider = tp
+	}
+}
+
+// newTracer creates a trace.Tracer from tp, or a no-op trace.Tracer if tp is
+// nil.
+func newTracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+
+	return tp.Tracer(instrumentationName)
+}
+
+// startSpan starts a span with the specified name for a transaction.
+func (db *DB) startSpan(ctx context.Context, name, op string, rw bool) (context.Context, trace.Span) {
+	return db.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("db.system", "boltdb"),
+			attribute.String("bolt.database", db.name),
+			attribute.String("bolt.operation", op),
+			attribute.Bool("bolt.rw", rw),
+		),
+	)
+}
+
+// endSpan ends a span for the transaction tx, which returned err.  tx is nil
+// if the transaction could not be begun.
+func (db *DB) endSpan(span trace.Span, tx *bolt.Tx, err error) {
+	defer span.End()
+
+	if !span.IsRecording() {
+		return
+	}
+
+	// Bolt leaves the statistics of a closed transaction in place.  Only
+	// write transactions are committed.
+	if tx != nil && tx.Writable() {
+		s := tx.Stats()
+		commit := s.RebalanceTime + s.SpillTime + s.WriteTime
+
+		span.SetAttributes(
+			attribute.Int("bolt.tx.pages_allocated", s.PageCount),
+			attribute.Int("bolt.tx.nodes_split", s.Split),
+			attribute.Int("bolt.tx.nodes_spilled", s.Spill),
+			attribute.Int("bolt.tx.writes", s.Write),
+			attribute.Float64("bolt.tx.commit_seconds", commit.Seconds()),
+		)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,128 @@
+package prombolt
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDBTracing(t *testing.T) {
+	bdb, done := testDB(t)
+	defer done()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	db := NewDB("test.db", bdb, TracerProvider(tp))
+
+	// Transactions are children of the span in the caller's context.
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	ctx = WithOp(ctx, "gc")
+
+	err := db.UpdateContext(ctx, func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("foo"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+
+	err = db.BatchContext(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("foo")).Put([]byte("foo"), []byte("bar"))
+	})
+	if err != nil {
+		t.Fatalf("failed to batch update database: %v", err)
+	}
+
+	errFoo := errors.New("foo")
+	err = db.ViewContext(ctx, func(tx *bolt.Tx) error {
+		return errFoo
+	})
+	if want, got := errFoo, err; want != got {
+		t.Fatalf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if want, got := 4, len(spans); want != got {
+		t.Fatalf("unexpected number of spans:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	tests := []struct {
+		name   string
+		rw     bool
+		status codes.Code
+	}{
+		{
+			name:   "bolt.Update",
+			rw:     true,
+			status: codes.Unset,
+		},
+		{
+			name:   "bolt.Batch",
+			rw:     true,
+			status: codes.Unset,
+		},
+		{
+			name:   "bolt.View",
+			rw:     false,
+			status: codes.Error,
+		},
+	}
+
+	for i, tt := range tests {
+		s := spans[i]
+
+		if want, got := tt.name, s.Name; want != got {
+			t.Fatalf("unexpected span name:\n- want: %q\n-  got: %q", want, got)
+		}
+
+		if want, got := trace.SpanKindInternal, s.SpanKind; want != got {
+			t.Fatalf("unexpected span kind:\n- want: %v\n-  got: %v", want, got)
+		}
+
+		if want, got := parent.SpanContext().SpanID(), s.Parent.SpanID(); want != got {
+			t.Fatalf("unexpected parent span ID:\n- want: %v\n-  got: %v", want, got)
+		}
+
+		if want, got := tt.status, s.Status.Code; want != got {
+			t.Fatalf("unexpected span status:\n- want: %v\n-  got: %v", want, got)
+		}
+
+		attrs := make(map[attribute.Key]attribute.Value)
+		for _, kv := range s.Attributes {
+			attrs[kv.Key] = kv.Value
+		}
+
+		if want, got := "test.db", attrs["bolt.database"].AsString(); want != got {
+			t.Fatalf("unexpected database:\n- want: %q\n-  got: %q", want, got)
+		}
+		if want, got := "gc", attrs["bolt.operation"].AsString(); want != got {
+			t.Fatalf("unexpected operation:\n- want: %q\n-  got: %q", want, got)
+		}
+		if want, got := tt.rw, attrs["bolt.rw"].AsBool(); want != got {
+			t.Fatalf("unexpected rw:\n- want: %v\n-  got: %v", want, got)
+		}
+
+		_, ok := attrs["bolt.tx.pages_allocated"]
+		if want, got := tt.rw, ok; want != got {
+			t.Fatalf("unexpected presence of transaction statistics:\n- want: %v\n-  got: %v",
+				want, got)
+		}
+		if tt.rw && attrs["bolt.tx.commit_seconds"].AsFloat64() <= 0 {
+			t.Fatal("expected nonzero commit duration")
+		}
+
+		if tt.status == codes.Error && len(s.Events) == 0 {
+			t.Fatal("expected error to be recorded")
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package prombolt
+
+import (
+	"bytes"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// bucketOther is the bucket label value used for operations on buckets
+	// whose names are not permitted, to keep the set of label values bounded.
+	// bucketLabel quotes names which begin with "<", so bucketOther is never
+	// the label value of a bucket.
+	bucketOther = "<other>"
+
+	// maxBuckets is the maximum number of distinct bucket names used as label
+	// values when no bucket names are specified using Buckets.
+	maxBuckets = 256
+)
+
+// Buckets sets the bucket names which may be used as label values for a DB's
+// bucket operation metrics.  Nested bucket names are the names of the parent
+// buckets and the nested bucket, separated by "/".  Names which are quoted in
+// label values, as described below, must be passed quoted.  Operations on any
+// other bucket are labeled "<other>".
+//
+// By default, the first 256 distinct bucket names are used as label values,
+// and any others are labeled "<other>".
+//
+// Bucket names which are not valid UTF-8, such as binary keys, or which
+// contain "/" or begin with `"` or "<", are quoted using Go syntax in label
+// values, as with strconv.Quote, so that no two buckets share a label value.
+func Buckets(names ...string) DBOption {
+	return func(c *dbConfig) {
+		c.buckets = names
+	}
+}
+
+// bucketLabel returns the label value for a bucket name.  Names which are not
+// valid UTF-8, such as binary keys, cannot be used as label values, and are
+// quoted.  Names which could be confused with a quoted name, a nested bucket
+// name, or bucketOther are quoted as well, so that each name has a distinct
+// label value.
+func bucketLabel(name []byte) string {
+	if utf8.Valid(name) &&
+		!bytes.ContainsRune(name, '/') &&
+		!bytes.HasPrefix(name, []byte(`"`)) &&
+		!bytes.HasPrefix(name, []byte("<")) {
+		return string(name)
+	}
+
+	return strconv.Quote(string(name))
+}
+
+// A Tx wraps a Bolt transaction, and records Prometheus metrics for
+// operations performed on the buckets it returns.  Tx values are created
+// using (*DB).WrapTx.
+//
+// All methods of the embedded *bolt.Tx which are not overridden by Tx do not
+// record metrics.
+type Tx struct {
+	*bolt.Tx
+	db *DB
+	ws *writeTxState
+}
+
+// WrapTx wraps a Bolt transaction so that operations performed on its
+// buckets are recorded by db.  Typically, WrapTx is called at the beginning
+// of a function passed to Update, Batch, or View.
+func (db *DB) WrapTx(tx *bolt.Tx) *Tx {
+	return &Tx{
+		Tx: tx,
+		db: db,
+		ws: db.trackedWriteTx(tx),
+	}
+}
+
+// Bucket retrieves a bucket by name, as with (*bolt.Tx).Bucket.  Returns nil
+// if the bucket does not exist.
+func (tx *Tx) Bucket(name []byte) *Bucket {
+	return tx.db.wrapBucket(tx.Tx.Bucket(name), bucketLabel(name), tx.ws)
+}
+
+// CreateBucket creates a new bucket, as with (*bolt.Tx).CreateBucket.
+func (tx *Tx) CreateBucket(name []byte) (*Bucket, error) {
+	b, err := tx.Tx.CreateBucket(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.db.wrapBucket(b, bucketLabel(name), tx.ws), nil
+}
+
+// CreateBucketIfNotExists creates a new bucket if it doesn't already exist,
+// as with (*bolt.Tx).CreateBucketIfNotExists.
+func (tx *Tx) CreateBucketIfNotExists(name []byte) (*Bucket, error) {
+	b, err := tx.Tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.db.wrapBucket(b, bucketLabel(name), tx.ws), nil
+}
+
+// A Bucket wraps a Bolt bucket, and records Prometheus metrics for
+// operations performed on it.  Bucket values are created using the methods
+// of Tx.
+//
+// Bucket's Bucket method would conflict with an embedded *bolt.Bucket, so the
+// most commonly used methods of *bolt.Bucket are provided by Bucket.  Use
+// Unwrap to access the *bolt.Bucket directly; operations performed on it are
+// not recorded.
+type Bucket struct {
+	b    *bolt.Bucket
+	db   *DB
+	name string
+	m    *bucketMetrics
+	ws   *writeTxState
+}
+
+// wrapBucket wraps b so its operations are recorded with the specified name.
+// If ws is not nil, bytes written to b are also recorded for the write
+// transaction.  If b is nil, wrapBucket returns nil.
+func (db *DB) wrapBucket(b *bolt.Bucket, name string, ws *writeTxState) *Bucket {
+	if b == nil {
+		return nil
+	}
+
+	return &Bucket{
+		b:    b,
+		db:   db,
+		name: name,
+		m:    db.bucketMetrics(name),
+		ws:   ws,
+	}
+}
+
+// Unwrap returns the *bolt.Bucket wrapped by b.
+func (b *Bucket) Unwrap() *bolt.Bucket {
+	return b.b
+}
+
+// Bucket retrieves a nested bucket by name, as with (*bolt.Bucket).Bucket.
+// Returns nil if the bucket does not exist.  Operations on nested buckets
+// are recorded using the names of the parent buckets and the nested bucket,
+// separated by "/".
+func (b *Bucket) Bucket(name []byte) *Bucket {
+	return b.db.wrapBucket(b.b.Bucket(name), b.childName(name), b.ws)
+}
+
+// CreateBucket creates a new nested bucket, as with
+// (*bolt.Bucket).CreateBucket.
+func (b *Bucket) CreateBucket(name []byte) (*Bucket, error) {
+	child, err := b.b.CreateBucket(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.db.wrapBucket(child, b.childName(name), b.ws), nil
+}
+
+// CreateBucketIfNotExists creates a new nested bucket if it doesn't already
+// exist, as with (*bolt.Bucket).CreateBucketIfNotExists.
+func (b *Bucket) CreateBucketIfNotExists(name []byte) (*Bucket, error) {
+	child, err := b.b.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.db.wrapBucket(child, b.childName(name), b.ws), nil
+}
+
+// childName returns the name used to record operations on the nested bucket
+// with the specified name.
+func (b *Bucket) childName(name []byte) string {
+	return b.name + "/" + bucketLabel(name)
+}
+
+// Get retrieves the value for a key, as with (*bolt.Bucket).Get.
+func (b *Bucket) Get(key []byte) []byte {
+	b.m.Get.Inc()
+	return b.b.Get(key)
+}
+
+// Put sets the value for a key, as with (*bolt.Bucket).Put.  If the value is
+// set, the number of bytes in the key and value are recorded.
+func (b *Bucket) Put(key []byte, value []byte) error {
+	b.m.Put.Inc()
+	if err := b.b.Put(key, value); err != nil {
+		return err
+	}
+
+	n := len(key) + len(value)
+	b.m.WrittenBytes.Add(float64(n))
+	if b.ws != nil {
+		b.ws.userBytes += n
+	}
+
+	return nil
+}
+
+// Delete removes a key, as with (*bolt.Bucket).Delete.
+func (b *Bucket) Delete(key []byte) error {
+	b.m.Delete.Inc()
+	return b.b.Delete(key)
+}
+
+// ForEach executes a function for each key/value pair in the bucket, as with
+// (*bolt.Bucket).ForEach.
+func (b *Bucket) ForEach(fn func(k, v []byte) error) error {
+	return b.b.ForEach(fn)
+}
+
+// DeleteBucket deletes a nested bucket, as with (*bolt.Bucket).DeleteBucket.
+func (b *Bucket) DeleteBucket(key []byte) error {
+	return b.b.DeleteBucket(key)
+}
+
+// NextSequence returns an autoincrementing integer for the bucket, as with
+// (*bolt.Bucket).NextSequence.
+func (b *Bucket) NextSequence() (uint64, error) {
+	return b.b.NextSequence()
+}
+
+// Sequence returns the current integer for the bucket, as with
+// (*bolt.Bucket).Sequence.
+func (b *Bucket) Sequence() uint64 {
+	return b.b.Sequence()
+}
+
+// SetSequence updates the sequence number for the bucket, as with
+// (*bolt.Bucket).SetSequence.
+func (b *Bucket) SetSequence(v uint64) error {
+	return b.b.SetSequence(v)
+}
+
+// Stats retrieves stats on the bucket, as with (*bolt.Bucket).Stats.
+func (b *Bucket) Stats() bolt.BucketStats {
+	return b.b.Stats()
+}
+
+// Writable returns whether the bucket is writable, as with
+// (*bolt.Bucket).Writable.
+func (b *Bucket) Writable() bool {
+	return b.b.Writable()
+}
+
+// Cursor creates a cursor associated with the bucket, as with
+// (*bolt.Bucket).Cursor.
+func (b *Bucket) Cursor() *Cursor {
+	return &Cursor{
+		Cursor: b.b.Cursor(),
+		m:      b.m,
+	}
+}
+
+// A Cursor wraps a Bolt cursor, and records Prometheus metrics for operations
+// performed with it.  Cursor values are created using (*Bucket).Cursor.
+type Cursor struct {
+	*bolt.Cursor
+	m *bucketMetrics
+}
+
+// First moves the cursor to the first item in the bucket, as with
+// (*bolt.Cursor).First.
+func (c *Cursor) First() (key []byte, value []byte) {
+	c.m.CursorFirst.Inc()
+	return c.Cursor.First()
+}
+
+// Last moves the cursor to the last item in the bucket, as with
+// (*bolt.Cursor).Last.
+func (c *Cursor) Last() (key []byte, value []byte) {
+	c.m.CursorLast.Inc()
+	return c.Cursor.Last()
+}
+
+// Next moves the cursor to the next item in the bucket, as with
+// (*bolt.Cursor).Next.
+func (c *Cursor) Next() (key []byte, value []byte) {
+	c.m.CursorNext.Inc()
+	return c.Cursor.Next()
+}
+
+// Prev moves the cursor to the previous item in the bucket, as with
+// (*bolt.Cursor).Prev.
+func (c *Cursor) Prev() (key []byte, value []byte) {
+	c.m.CursorPrev.Inc()
+	return c.Cursor.Prev()
+}
+
+// Seek moves the cursor to a given key, as with (*bolt.Cursor).Seek.
+func (c *Cursor) Seek(seek []byte) (key []byte, value []byte) {
+	c.m.CursorSeek.Inc()
+	return c.Cursor.Seek(seek)
+}
+
+// Delete removes the current key/value under the cursor, as with
+// (*bolt.Cursor).Delete.
+func (c *Cursor) Delete() error {
+	c.m.Delete.Inc()
+	return c.Cursor.Delete()
+}
+
+// bucketMetrics contains the Prometheus metrics for operations on a single
+// bucket, so that label values need not be resolved for each operation.
+type bucketMetrics struct {
+	Get          prometheus.Counter
+	Put          prometheus.Counter
+	Delete       prometheus.Counter
+	CursorFirst  prometheus.Counter
+	CursorLast   prometheus.Counter
+	CursorNext   prometheus.Counter
+	CursorPrev   prometheus.Counter
+	CursorSeek   prometheus.Counter
+	WrittenBytes prometheus.Counter
+}
+
+// discardCounter is an unregistered prometheus.Counter used when the metrics
+// for a bucket cannot be created, so that no operation on the bucket panics.
+var discardCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "bolt_bucket_discarded_total",
+	Help: "Discarded bucket operations.",
+})
+
+// bucketMetrics returns the bucketMetrics for the bucket with the specified
+// name, creating them if needed.  Buckets whose names are not permitted as
+// label values share the bucketMetrics for bucketOther.
+func (db *DB) bucketMetrics(bucket string) *bucketMetrics {
+	db.bucketMu.Lock()
+	defer db.bucketMu.Unlock()
+
+	if m, ok := db.buckets[bucket]; ok {
+		return m
+	}
+
+	if !db.bucketPermitted(bucket) {
+		bucket = bucketOther
+		if m, ok := db.buckets[bucket]; ok {
+			return m
+		}
+	}
+
+	// Bucket operations happen within the caller's transaction, so metrics
+	// which cannot be created are discarded rather than panicking.
+	counter := func(v *prometheus.CounterVec, lvs ...string) prometheus.Counter {
+		c, err := v.GetMetricWithLabelValues(lvs...)
+		if err != nil {
+			return discardCounter
+		}
+
+		return c
+	}
+
+	op := func(op string) prometheus.Counter {
+		return counter(db.bucketOps, db.name, bucket, op)
+	}
+
+	m := &bucketMetrics{
+		Get:          op("get"),
+		Put:          op("put"),
+		Delete:       op("delete"),
+		CursorFirst:  op("cursor_first"),
+		CursorLast:   op("cursor_last"),
+		CursorNext:   op("cursor_next"),
+		CursorPrev:   op("cursor_prev"),
+		CursorSeek:   op("cursor_seek"),
+		WrittenBytes: counter(db.bucketWrittenBytes, db.name, bucket),
+	}
+
+	db.buckets[bucket] = m
+	return m
+}
+
+// bucketPermitted reports whether bucket may be used as a label value.  The
+// caller must hold db.bucketMu.
+func (db *DB) bucketPermitted(bucket string) bool {
+	if db.bucketsFixed {
+		_, ok := db.bucketNames[bucket]
+		return ok
+	}
+
+	return len(db.buckets) < maxBuckets
+}
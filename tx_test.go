@@ -0,0 +1,258 @@
+package prombolt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestTxBucketOperations(t *testing.T) {
+	bdb, done := testDB(t)
+	defer done()
+
+	db := NewDB("test.db", bdb)
+
+	err := db.Update(func(btx *bolt.Tx) error {
+		tx := db.WrapTx(btx)
+
+		b, err := tx.CreateBucket([]byte("foo"))
+		if err != nil {
+			return err
+		}
+
+		if err := b.Put([]byte("key"), []byte("value")); err != nil {
+			return err
+		}
+		if err := b.Put([]byte("foo"), []byte("bar")); err != nil {
+			return err
+		}
+		if err := b.Delete([]byte("foo")); err != nil {
+			return err
+		}
+
+		child, err := b.CreateBucketIfNotExists([]byte("bar"))
+		if err != nil {
+			return err
+		}
+
+		return child.Put([]byte("k"), []byte("v"))
+	})
+	if err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+
+	err = db.View(func(btx *bolt.Tx) error {
+		tx := db.WrapTx(btx)
+
+		if b := tx.Bucket([]byte("baz")); b != nil {
+			t.Fatalf("expected nil bucket, but got: %v", b)
+		}
+
+		b := tx.Bucket([]byte("foo"))
+		if want, got := "value", string(b.Get([]byte("key"))); want != got {
+			t.Fatalf("unexpected value:\n- want: %v\n-  got: %v", want, got)
+		}
+
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		}
+		c.Seek([]byte("key"))
+
+		b.Bucket([]byte("bar")).Get([]byte("k"))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to view database: %v", err)
+	}
+
+	got := testCollector(t, db)
+
+	matches := []string{
+		`bolt_bucket_operations_total{bucket="foo",database="test.db",op="put"} 2`,
+		`bolt_bucket_operations_total{bucket="foo",database="test.db",op="delete"} 1`,
+		`bolt_bucket_operations_total{bucket="foo",database="test.db",op="get"} 1`,
+		`bolt_bucket_operations_total{bucket="foo",database="test.db",op="cursor_first"} 1`,
+		// One key and one nested bucket, plus the final call which returns nil.
+		`bolt_bucket_operations_total{bucket="foo",database="test.db",op="cursor_next"} 2`,
+		`bolt_bucket_operations_total{bucket="foo",database="test.db",op="cursor_seek"} 1`,
+		`bolt_bucket_operations_total{bucket="foo/bar",database="test.db",op="put"} 1`,
+		`bolt_bucket_operations_total{bucket="foo/bar",database="test.db",op="get"} 1`,
+		`bolt_bucket_written_bytes_total{bucket="foo",database="test.db"} 14`,
+		`bolt_bucket_written_bytes_total{bucket="foo/bar",database="test.db"} 2`,
+	}
+
+	for _, m := range matches {
+		if !strings.Contains(got, m) {
+			t.Fatalf("output did not contain expected metric: %q", m)
+		}
+	}
+}
+
+func TestTxBucketLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []DBOption
+		buckets int
+		matches []string
+	}{
+		{
+			name: "not UTF-8",
+			matches: []string{
+				`bolt_bucket_operations_total{bucket="\"\\xff\\x01\"",database="test.db",op="put"} 1`,
+				`bolt_bucket_operations_total{bucket="\"\\xff\\x01\"/\"\\xfe\"",database="test.db",op="put"} 1`,
+			},
+		},
+		{
+			name:    "fixed",
+			options: []DBOption{Buckets(`"\xff\x01"`)},
+			matches: []string{
+				`bolt_bucket_operations_total{bucket="\"\\xff\\x01\"",database="test.db",op="put"} 1`,
+				`bolt_bucket_operations_total{bucket="<other>",database="test.db",op="put"} 1`,
+			},
+		},
+		{
+			name:    "limit",
+			buckets: maxBuckets,
+			matches: []string{
+				`bolt_bucket_operations_total{bucket="<other>",database="test.db",op="put"} 2`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bdb, done := testDB(t)
+			defer done()
+
+			db := NewDB("test.db", bdb, tt.options...)
+
+			err := db.Update(func(btx *bolt.Tx) error {
+				tx := db.WrapTx(btx)
+
+				// Fill the set of bucket label values, if needed.
+				for i := 0; i < tt.buckets; i++ {
+					if _, err := tx.CreateBucket([]byte(fmt.Sprintf("bucket%03d", i))); err != nil {
+						return err
+					}
+				}
+
+				b, err := tx.CreateBucket([]byte{0xff, 0x01})
+				if err != nil {
+					return err
+				}
+				if err := b.Put([]byte("k"), []byte("v")); err != nil {
+					return err
+				}
+
+				child, err := b.CreateBucket([]byte{0xfe})
+				if err != nil {
+					return err
+				}
+
+				return child.Put([]byte("k"), []byte("v"))
+			})
+			if err != nil {
+				t.Fatalf("failed to update database: %v", err)
+			}
+
+			got := testCollector(t, db)
+
+			for _, m := range tt.matches {
+				if !strings.Contains(got, m) {
+					t.Fatalf("output did not contain expected metric: %q", m)
+				}
+			}
+		})
+	}
+}
+
+func TestBucketLabelDistinct(t *testing.T) {
+	// Each name must have a distinct label value, which is never bucketOther.
+	names := [][]byte{
+		{0xff},
+		[]byte("0xff"),
+		[]byte(`"\xff"`),
+		[]byte("other"),
+		[]byte("<other>"),
+		[]byte("a/b"),
+		[]byte(`"a/b"`),
+		[]byte("a"),
+	}
+
+	seen := map[string][]byte{
+		bucketOther: nil,
+	}
+
+	for _, n := range names {
+		l := bucketLabel(n)
+		if prev, ok := seen[l]; ok {
+			t.Fatalf("names %q and %q have the same label value: %q", prev, n, l)
+		}
+
+		seen[l] = n
+	}
+
+	// A nested bucket name must not match the name of a top-level bucket.
+	b := &Bucket{name: bucketLabel([]byte("a"))}
+	if l := b.childName([]byte("b")); l == bucketLabel([]byte("a/b")) {
+		t.Fatalf("nested bucket has the same label value as a top-level bucket: %q", l)
+	}
+}
+
+func TestTxWriteAmplification(t *testing.T) {
+	bdb, done := testDB(t)
+	defer done()
+
+	db := NewDB("test.db", bdb)
+
+	err := db.Update(func(btx *bolt.Tx) error {
+		b, err := db.WrapTx(btx).CreateBucket([]byte("foo"))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte("key"), make([]byte, 97))
+	})
+	if err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+
+	// Transactions which do not use a Bucket write no user bytes, and are not
+	// included in the write amplification distribution.
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("foo")).Put([]byte("bar"), nil)
+	})
+	if err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+
+	got := testCollector(t, db)
+
+	matches := []string{
+		`bolt_write_tx_user_written_bytes_total{database="test.db"} 100`,
+		`bolt_write_tx_write_amplification_count{database="test.db",operation="unknown"} 1`,
+	}
+
+	for _, m := range matches {
+		if !strings.Contains(got, m) {
+			t.Fatalf("output did not contain expected metric: %q", m)
+		}
+	}
+
+	var diskBytes int
+	for _, l := range strings.Split(got, "\n") {
+		if strings.HasPrefix(l, `bolt_write_tx_disk_written_bytes_total{database="test.db"}`) {
+			if _, err := fmt.Sscanf(l, `bolt_write_tx_disk_written_bytes_total{database="test.db"} %d`, &diskBytes); err != nil {
+				t.Fatalf("failed to parse metric: %v", err)
+			}
+		}
+	}
+
+	// Each transaction writes at least one data page and one meta page.
+	if min := 4 * os.Getpagesize(); diskBytes < min {
+		t.Fatalf("unexpectedly few bytes written to disk: %d < %d", diskBytes, min)
+	}
+}